@@ -0,0 +1,129 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"path/filepath"
+)
+
+const jsonContentType = "application/json; charset=utf-8"
+
+// check tells if the answer of a route is valid: json, body and file
+// can't be used together, the states of a scenario can have variants which
+// can have a sequence, but not the other way around.
+func (r raw) check() error {
+	return r.checkIn("", r.Scenario)
+}
+
+// checkIn checks an answer which is a state, a variant or a step of a
+// sequence, or the answer of the route when kind is empty.
+func (r raw) checkIn(kind, scenario string) error {
+	if kind != "" && (r.Scenario != "" || len(r.States) > 0) {
+		return fmt.Errorf("a %s can't have a scenario", kind)
+	}
+	if scenario == "" && (len(r.States) > 0 || r.Next != "") {
+		return errors.New("states and next need a scenario")
+	}
+	if len(r.Variants) > 0 && (kind == "variant" || kind == "step") {
+		return fmt.Errorf("a %s can't have variants", kind)
+	}
+	if len(r.Sequence) > 0 && kind == "step" {
+		return errors.New("a step can't have a sequence")
+	}
+	for _, name := range sortedKeys(r.States) {
+		if err := r.States[name].checkIn("state", scenario); err != nil {
+			return fmt.Errorf("state %s: %v", name, err)
+		}
+	}
+	for i, v := range r.Variants {
+		if err := v.checkIn("variant", scenario); err != nil {
+			return fmt.Errorf("variant %d: %v", i+1, err)
+		}
+	}
+	for i, step := range r.Sequence {
+		if err := step.checkIn("step", scenario); err != nil {
+			return fmt.Errorf("step %d: %v", i+1, err)
+		}
+	}
+	given := 0
+	for _, set := range []bool{r.JSON != nil, r.Body != "", r.File != ""} {
+		if set {
+			given++
+		}
+	}
+	if given > 1 {
+		return errors.New("only one of json, body and file can be given")
+	}
+	return nil
+}
+
+// hasBody tells if the raw gives a body, or states, variants or steps
+// which can.
+func (r raw) hasBody() bool {
+	return r.JSON != nil || r.Body != "" || r.File != "" ||
+		len(r.States) > 0 || len(r.Variants) > 0 || len(r.Sequence) > 0
+}
+
+// body returns the body to write and its content type. The content type of
+// a file is found from its extension, or from its first bytes.
+func (r raw) body() ([]byte, string, error) {
+	var body []byte
+	contentType := r.ContentType
+	switch {
+	case r.File != "":
+		data, err := ioutil.ReadFile(r.File)
+		if err != nil {
+			return nil, "", err
+		}
+		body = data
+		if contentType == "" {
+			contentType = mime.TypeByExtension(filepath.Ext(r.File))
+		}
+		if contentType == "" {
+			contentType = http.DetectContentType(data)
+		}
+	case r.Body != "":
+		body = []byte(r.Body)
+		if contentType == "" {
+			contentType = "text/plain; charset=utf-8"
+		}
+	default:
+		body = r.JSON
+		if contentType == "" {
+			contentType = jsonContentType
+		}
+	}
+	return body, contentType, nil
+}
+
+// resolveFiles makes the paths of the body files relative to dir, the
+// directory of the db file declaring the routes.
+func (dbc *dbContent) resolveFiles(dir string) {
+	var resolve func(r *raw)
+	resolve = func(r *raw) {
+		if r.File != "" && !filepath.IsAbs(r.File) {
+			r.File = filepath.Join(dir, r.File)
+		}
+		for name, stateRaw := range r.States {
+			resolve(&stateRaw)
+			r.States[name] = stateRaw
+		}
+		for i := range r.Variants {
+			resolve(&r.Variants[i].raw)
+		}
+		for i := range r.Sequence {
+			resolve(&r.Sequence[i])
+		}
+	}
+	for key, rt := range dbc.URLs {
+		resolve(&rt.raw)
+		for method, methodRaw := range rt.Methods {
+			resolve(&methodRaw)
+			rt.Methods[method] = methodRaw
+		}
+		dbc.URLs[key] = rt
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// chaosConfig breaks the answers of a route at random, to test how the
+// frontend handles a failing backend. The rates are between 0 and 1.
+type chaosConfig struct {
+	// ErrorRate answers with ErrorStatus, 500 by default.
+	ErrorRate   float64 `json:"errorrate"`
+	ErrorStatus int     `json:"errorstatus"`
+	// ResetRate closes the connection without answering.
+	ResetRate float64 `json:"resetrate"`
+	// TruncateRate closes the connection in the middle of the body.
+	TruncateRate float64 `json:"truncaterate"`
+	// MalformedRate sends a body that isn't valid JSON.
+	MalformedRate float64 `json:"malformedrate"`
+	// Trickle writes the body by chunks of Chunk bytes every Interval
+	// milliseconds.
+	Trickle *trickleConfig `json:"trickle"`
+}
+
+type trickleConfig struct {
+	Chunk    int `json:"chunk"`
+	Interval int `json:"interval"`
+}
+
+// write writes the answer, broken as configured.
+func (conf *chaosConfig) write(w http.ResponseWriter, r *http.Request, status int, body []byte) {
+	if conf == nil {
+		w.WriteHeader(status)
+		w.Write(body)
+		return
+	}
+	if roll(conf.ResetRate) {
+		reset(w)
+		return
+	}
+	if roll(conf.ErrorRate) {
+		errorStatus := conf.ErrorStatus
+		if errorStatus == 0 {
+			errorStatus = http.StatusInternalServerError
+		}
+		writeError(w, errorStatus, fmt.Errorf("fault injected by the chaos configuration"))
+		return
+	}
+	if roll(conf.TruncateRate) && len(body) > 0 {
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		w.WriteHeader(status)
+		conf.Trickle.write(w, r, body[:rand.Intn(len(body))])
+		// the connection is closed by the server with the body incomplete
+		panic(http.ErrAbortHandler)
+	}
+	if roll(conf.MalformedRate) {
+		body = malformed(body)
+	}
+	w.WriteHeader(status)
+	conf.Trickle.write(w, r, body)
+}
+
+func (conf *trickleConfig) write(w http.ResponseWriter, r *http.Request, body []byte) {
+	if conf == nil || conf.Chunk <= 0 {
+		w.Write(body)
+		return
+	}
+	flusher, _ := w.(http.Flusher)
+	for len(body) > 0 {
+		n := conf.Chunk
+		if n > len(body) {
+			n = len(body)
+		}
+		if _, err := w.Write(body[:n]); err != nil {
+			return
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		body = body[n:]
+		if len(body) > 0 {
+			wait(r, time.Duration(conf.Interval)*time.Millisecond)
+		}
+	}
+}
+
+// reset closes the connection right away, with a TCP reset when possible.
+func reset(w http.ResponseWriter) {
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		panic(http.ErrAbortHandler)
+	}
+	conn, _, err := hijacker.Hijack()
+	if err != nil {
+		panic(http.ErrAbortHandler)
+	}
+	if tcp, ok := conn.(*net.TCPConn); ok {
+		tcp.SetLinger(0)
+	}
+	conn.Close()
+}
+
+// malformed returns the body without its last character and with a comma
+// instead, which is never valid JSON.
+func malformed(body []byte) []byte {
+	if len(body) == 0 {
+		return []byte(",")
+	}
+	broken := make([]byte, len(body))
+	copy(broken, body)
+	broken[len(broken)-1] = ','
+	return broken
+}
+
+func roll(rate float64) bool {
+	return rate > 0 && rand.Float64() < rate
+}
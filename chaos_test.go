@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestChaos(t *testing.T) {
+	handler, err := NewJSONHandler("testdata/db_chaos.json", true)
+	if err != nil {
+		t.Fatalf("An error occured when loading the file: %v", err)
+	}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	tests := []struct {
+		requestPath    string
+		expectedStatus int
+		expectedErr    bool
+		validJSON      bool
+		expectedMin    time.Duration
+	}{
+		{requestPath: "/error", expectedStatus: http.StatusServiceUnavailable, validJSON: true},
+		{requestPath: "/reset", expectedErr: true},
+		{requestPath: "/truncate", expectedStatus: http.StatusOK, expectedErr: true},
+		{requestPath: "/malformed", expectedStatus: http.StatusOK, validJSON: false},
+		{requestPath: "/trickle", expectedStatus: http.StatusOK, validJSON: true, expectedMin: 30 * time.Millisecond},
+		{requestPath: "/never", expectedStatus: http.StatusOK, validJSON: true},
+	}
+	for i, test := range tests {
+		start := time.Now()
+		resp, err := http.Get(server.URL + test.requestPath)
+		if err != nil {
+			if !test.expectedErr {
+				t.Fatalf("Test %d: an error occured when sending the request: %v", i, err)
+			}
+			continue
+		}
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		elapsed := time.Since(start)
+		if (err != nil) != test.expectedErr {
+			t.Fatalf("Test %d: expected error %t, got %v", i, test.expectedErr, err)
+		}
+		if resp.StatusCode != test.expectedStatus {
+			t.Fatalf("Test %d: expected status: %d, got %d", i, test.expectedStatus, resp.StatusCode)
+		}
+		if err != nil {
+			continue
+		}
+		var v interface{}
+		if valid := json.Unmarshal(body, &v) == nil; valid != test.validJSON {
+			t.Fatalf("Test %d: expected valid JSON %t, got body %s", i, test.validJSON, body)
+		}
+		if elapsed < test.expectedMin {
+			t.Fatalf("Test %d: the answer took %v, expected at least %v", i, elapsed, test.expectedMin)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"math/rand"
+)
+
+// isChoice tells if the type picks among the values of the function.
+func isChoice(typ string) bool {
+	return typ == "choice" || typ == "weighted" || typ == "sample"
+}
+
+// pick returns one of the values, taking the weights into account if
+// there are as many as values.
+func (p random) pick(rnd *rand.Rand) json.RawMessage {
+	if len(p.Weights) != len(p.Values) {
+		return p.Values[rnd.Intn(len(p.Values))]
+	}
+	var total float64
+	for _, w := range p.Weights {
+		total += w
+	}
+	target := rnd.Float64() * total
+	for i, w := range p.Weights {
+		if target < w {
+			return p.Values[i]
+		}
+		target -= w
+	}
+	return p.Values[len(p.Values)-1]
+}
+
+// sample returns an array of distinct values, with size values or between
+// min and max, in a random order. The bounds are clamped to the number of
+// values, and a negative min is read as 0.
+func (p random) sample(rnd *rand.Rand) []json.RawMessage {
+	n := len(p.Values)
+	switch {
+	case p.Size > 0 && p.Size < n:
+		n = p.Size
+	case p.Max > p.Min && p.Min < n:
+		min, max := p.Min, p.Max
+		if min < 0 {
+			min = 0
+		}
+		if max > n {
+			max = n
+		}
+		if max < min {
+			max = min
+		}
+		n = min + rnd.Intn(max-min+1)
+	}
+	values := make([]json.RawMessage, n)
+	for i, index := range rnd.Perm(len(p.Values))[:n] {
+		values[i] = p.Values[index]
+	}
+	return values
+}
+
+// choiceFunc returns the function of a choice, weighted or sample type. The
+// values are written as JSON, so the strings keep their quotes.
+func (p random) choiceFunc(rnd *rand.Rand) func() (string, error) {
+	return func() (string, error) {
+		if p.Type != "sample" {
+			return string(p.pick(rnd)), nil
+		}
+		b, err := json.Marshal(p.sample(rnd))
+		if err != nil {
+			return "", err
+		}
+		return string(b), nil
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestChoice(t *testing.T) {
+	params := funcParams{
+		Randoms: map[string]random{
+			"status": random{
+				Type:   "choice",
+				Values: []json.RawMessage{json.RawMessage(`"pending"`), json.RawMessage(`"paid"`), json.RawMessage(`{"code": 3}`)},
+			},
+			"role": random{
+				Type:    "weighted",
+				Values:  []json.RawMessage{json.RawMessage(`"admin"`), json.RawMessage(`"user"`)},
+				Weights: []float64{0, 1},
+			},
+			"tags": random{
+				Type:   "sample",
+				Size:   2,
+				Values: []json.RawMessage{json.RawMessage(`"a"`), json.RawMessage(`"b"`), json.RawMessage(`"c"`)},
+			},
+			"empty": random{
+				Type: "choice",
+			},
+		},
+		Arrays: map[string]array{
+			"currencies": array{
+				Type:      "choice",
+				ArraySize: 5,
+				Values:    []json.RawMessage{json.RawMessage(`"EUR"`), json.RawMessage(`"USD"`)},
+			},
+		},
+	}
+	functions := params.parse()
+	if _, ok := functions["empty"]; ok {
+		t.Fatalf("Expected the choice without values to be ignored")
+	}
+	call := func(name string) string {
+		f, ok := functions[name].(func() (string, error))
+		if !ok {
+			t.Fatalf("Error when parsing the function %s, the actual type is %T", name, functions[name])
+		}
+		result, err := f()
+		if err != nil {
+			t.Fatalf("An error occured when execution the function: %v", err)
+		}
+		return result
+	}
+	for n := 0; n < 50; n++ {
+		switch status := call("status"); status {
+		case `"pending"`, `"paid"`, `{"code": 3}`:
+		default:
+			t.Fatalf("The status %s isn't one of the values", status)
+		}
+		if role := call("role"); role != `"user"` {
+			t.Fatalf("Expected the role with all the weight, got %s", role)
+		}
+		var tags []string
+		if err := json.Unmarshal([]byte(call("tags")), &tags); err != nil {
+			t.Fatalf("Error while unmarshalling the tags: %v", err)
+		}
+		if len(tags) != 2 || tags[0] == tags[1] {
+			t.Fatalf("Expected 2 distinct tags, got %v", tags)
+		}
+		var currencies []string
+		if err := json.Unmarshal([]byte(call("currencies")), &currencies); err != nil {
+			t.Fatalf("Error while unmarshalling the currencies: %v", err)
+		}
+		if len(currencies) != 5 {
+			t.Fatalf("Expected 5 currencies, got %v", currencies)
+		}
+	}
+}
+
+func TestSampleBounds(t *testing.T) {
+	values := []json.RawMessage{json.RawMessage(`1`), json.RawMessage(`2`), json.RawMessage(`3`)}
+	tests := []struct {
+		min, max int
+	}{
+		{min: -5, max: 2},
+		{min: -5, max: -2},
+		{min: 1, max: 10},
+	}
+	rnd := newRand(1)
+	for i, test := range tests {
+		p := random{Type: "sample", Values: values, Min: test.min, Max: test.max}
+		for n := 0; n < 50; n++ {
+			if sample := p.sample(rnd); len(sample) > len(values) {
+				t.Fatalf("Test %d: expected at most %d values, got %d", i, len(values), len(sample))
+			}
+		}
+	}
+}
+
+func TestWeightedPick(t *testing.T) {
+	p := random{
+		Type:    "weighted",
+		Values:  []json.RawMessage{json.RawMessage(`1`), json.RawMessage(`2`)},
+		Weights: []float64{9, 1},
+	}
+	rnd := newRand(1)
+	counts := make(map[string]int)
+	for n := 0; n < 10000; n++ {
+		counts[string(p.pick(rnd))]++
+	}
+	if counts["1"] < 8500 || counts["1"] > 9500 {
+		t.Fatalf("Expected about 9000 picks of the first value, got %d", counts["1"])
+	}
+}
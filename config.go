@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const defaultPort = 3000
+
+// serverConfig is the server section of the db file. The address and the
+// port are only read when starting.
+type serverConfig struct {
+	Address  string `json:"address"`
+	Port     int    `json:"port"`
+	BasePath string `json:"basepath"`
+}
+
+// merge returns the configuration with the fields set in override
+// replacing its own.
+func (conf serverConfig) merge(override serverConfig) serverConfig {
+	if override.Address != "" {
+		conf.Address = override.Address
+	}
+	if override.Port != 0 {
+		conf.Port = override.Port
+	}
+	if override.BasePath != "" {
+		conf.BasePath = override.BasePath
+	}
+	return conf
+}
+
+// envServerConfig reads the configuration given by the ISEVA_ADDRESS,
+// ISEVA_PORT and ISEVA_BASE_PATH environment variables.
+func envServerConfig() (serverConfig, error) {
+	conf := serverConfig{
+		Address:  os.Getenv("ISEVA_ADDRESS"),
+		BasePath: os.Getenv("ISEVA_BASE_PATH"),
+	}
+	if port := os.Getenv("ISEVA_PORT"); port != "" {
+		var err error
+		if conf.Port, err = strconv.Atoi(port); err != nil {
+			return conf, fmt.Errorf("ISEVA_PORT: %v", err)
+		}
+	}
+	return conf, nil
+}
+
+func (conf serverConfig) listenAddr() string {
+	port := conf.Port
+	if port == 0 {
+		port = defaultPort
+	}
+	return net.JoinHostPort(conf.Address, strconv.Itoa(port))
+}
+
+// stripBasePath returns the path without the base path, false if the path
+// isn't under it.
+func stripBasePath(path, basePath string) (string, bool) {
+	basePath = "/" + strings.Trim(basePath, "/")
+	if basePath == "/" {
+		return path, true
+	}
+	if path == basePath {
+		return "/", true
+	}
+	if strings.HasPrefix(path, basePath+"/") {
+		return path[len(basePath):], true
+	}
+	return "", false
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestServerConfig(t *testing.T) {
+	handler, err := NewJSONHandler("testdata/db_server.json", true)
+	if err != nil {
+		t.Fatalf("An error occured when loading the file: %v", err)
+	}
+	os.Setenv("ISEVA_PORT", "9090")
+	defer os.Unsetenv("ISEVA_PORT")
+	env, err := envServerConfig()
+	if err != nil {
+		t.Fatalf("An error occured when reading the environment: %v", err)
+	}
+	tests := []struct {
+		override     serverConfig
+		expectedAddr string
+		expectedBase string
+	}{
+		{
+			expectedAddr: "127.0.0.1:8080",
+			expectedBase: "/api",
+		},
+		{
+			override:     env,
+			expectedAddr: "127.0.0.1:9090",
+			expectedBase: "/api",
+		},
+		{
+			override:     env.merge(serverConfig{Address: "0.0.0.0", BasePath: "/v2"}),
+			expectedAddr: "0.0.0.0:9090",
+			expectedBase: "/v2",
+		},
+	}
+	for i, test := range tests {
+		conf := handler.serverConfig().merge(test.override)
+		if conf.listenAddr() != test.expectedAddr {
+			t.Fatalf("Test %d: expected address %s, got %s", i, test.expectedAddr, conf.listenAddr())
+		}
+		if conf.BasePath != test.expectedBase {
+			t.Fatalf("Test %d: expected base path %s, got %s", i, test.expectedBase, conf.BasePath)
+		}
+	}
+	if addr := (serverConfig{}).listenAddr(); addr != ":3000" {
+		t.Fatalf("Expected the default address :3000, got %s", addr)
+	}
+
+	os.Setenv("ISEVA_PORT", "port")
+	if _, err := envServerConfig(); err == nil {
+		t.Fatalf("Expected error, got none")
+	}
+}
+
+func TestBasePath(t *testing.T) {
+	tests := []struct {
+		basePath        string
+		requestPath     string
+		expectedStatus  int
+		expectedContent string
+	}{
+		{
+			requestPath:     "/api/test",
+			expectedStatus:  http.StatusOK,
+			expectedContent: `{"field": "value"}`,
+		},
+		{
+			requestPath:     "/api",
+			expectedStatus:  http.StatusOK,
+			expectedContent: `{"root": true}`,
+		},
+		{
+			requestPath:     "/api/users/1",
+			expectedStatus:  http.StatusOK,
+			expectedContent: `{"id":1}`,
+		},
+		{
+			requestPath:     "/test",
+			expectedStatus:  http.StatusNotFound,
+			expectedContent: "",
+		},
+		{
+			requestPath:     "/apitest",
+			expectedStatus:  http.StatusNotFound,
+			expectedContent: "",
+		},
+		{
+			basePath:        "/v2/",
+			requestPath:     "/v2/test",
+			expectedStatus:  http.StatusOK,
+			expectedContent: `{"field": "value"}`,
+		},
+	}
+	for i, test := range tests {
+		handler := JSONHandler{DB: "testdata/db_server.json", BasePath: test.basePath}
+		req, err := http.NewRequest("GET", test.requestPath, nil)
+		if err != nil {
+			t.Fatalf("An error occured when creating the request: %v for test %d", err, i)
+		}
+		rec := httptest.NewRecorder()
+
+		handler.ServeHTTP(rec, req)
+		if rec.Code != test.expectedStatus {
+			t.Fatalf("Test %d, expected status: %d, got %d", i, test.expectedStatus, rec.Code)
+		}
+		respBody := rec.Body.String()
+		if respBody != test.expectedContent {
+			t.Fatalf("Test %d, expected body %s, got %s", i, test.expectedContent, respBody)
+		}
+	}
+}
+
+func TestBasePathLocation(t *testing.T) {
+	handler := JSONHandler{DB: "testdata/db_server.json"}
+	req, err := http.NewRequest("POST", "/api/users", strings.NewReader(`{"name": "John"}`))
+	if err != nil {
+		t.Fatalf("An error occured when creating the request: %v", err)
+	}
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status: %d, got %d", http.StatusCreated, rec.Code)
+	}
+	if location := rec.Header().Get("Location"); location != "/api/users/2" {
+		t.Fatalf("Expected the location under the base path, got %s", location)
+	}
+}
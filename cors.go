@@ -0,0 +1,113 @@
+package main
+
+import (
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+)
+
+var (
+	defaultCORSMethods = []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE"}
+	defaultCORSHeaders = []string{"Content-Type", "X-Requested-With"}
+)
+
+// corsConfig is the cors section of the db file, which a route can
+// override field by field with its own cors object.
+//
+// An origin can be "*" or contain * as a wildcard, like
+// "https://*.example.com". When no origin is given, every origin is
+// allowed. A header "*" allows the headers asked by the preflight request.
+type corsConfig struct {
+	Origins        []string `json:"origins"`
+	Methods        []string `json:"methods"`
+	Headers        []string `json:"headers"`
+	ExposedHeaders []string `json:"exposedheaders"`
+	Credentials    *bool    `json:"credentials"`
+	MaxAge         int      `json:"maxage"`
+}
+
+// merge returns the configuration with the fields set in override
+// replacing its own.
+func (conf corsConfig) merge(override *corsConfig) corsConfig {
+	if override == nil {
+		return conf
+	}
+	if override.Origins != nil {
+		conf.Origins = override.Origins
+	}
+	if override.Methods != nil {
+		conf.Methods = override.Methods
+	}
+	if override.Headers != nil {
+		conf.Headers = override.Headers
+	}
+	if override.ExposedHeaders != nil {
+		conf.ExposedHeaders = override.ExposedHeaders
+	}
+	if override.Credentials != nil {
+		conf.Credentials = override.Credentials
+	}
+	if override.MaxAge != 0 {
+		conf.MaxAge = override.MaxAge
+	}
+	return conf
+}
+
+func (conf corsConfig) allowOrigin(origin string) bool {
+	if len(conf.Origins) == 0 {
+		return true
+	}
+	for _, allowed := range conf.Origins {
+		if allowed == "*" || allowed == origin {
+			return true
+		}
+		if matched, err := path.Match(allowed, origin); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+// apply writes the CORS headers of the answer, with the ones only needed
+// by a preflight request if it is one.
+func (conf corsConfig) apply(w http.ResponseWriter, r *http.Request, preflight bool) {
+	h := w.Header()
+	h.Add("Vary", "Origin")
+	headers := conf.Headers
+	if headers == nil {
+		headers = defaultCORSHeaders
+	}
+	if len(headers) == 1 && headers[0] == "*" {
+		if requested := r.Header.Get("Access-Control-Request-Headers"); requested != "" {
+			h.Set("Access-Control-Allow-Headers", requested)
+		}
+	} else {
+		h.Set("Access-Control-Allow-Headers", strings.Join(headers, ", "))
+	}
+
+	origin := r.Header.Get("Origin")
+	if origin == "" || !conf.allowOrigin(origin) {
+		return
+	}
+	// the origin is always written back, as "*" isn't allowed with the
+	// credentials
+	h.Set("Access-Control-Allow-Origin", origin)
+	if conf.Credentials != nil && *conf.Credentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+	if len(conf.ExposedHeaders) > 0 {
+		h.Set("Access-Control-Expose-Headers", strings.Join(conf.ExposedHeaders, ", "))
+	}
+	if !preflight {
+		return
+	}
+	methods := conf.Methods
+	if methods == nil {
+		methods = defaultCORSMethods
+	}
+	h.Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+	if conf.MaxAge > 0 {
+		h.Set("Access-Control-Max-Age", strconv.Itoa(conf.MaxAge))
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCORS(t *testing.T) {
+	handler := JSONHandler{DB: "testdata/db_cors.json"}
+	tests := []struct {
+		method          string
+		requestPath     string
+		headers         map[string]string
+		expectedStatus  int
+		expectedHeaders map[string]string
+	}{
+		{
+			method:         "OPTIONS",
+			requestPath:    "/test",
+			headers:        map[string]string{"Origin": "http://localhost:8080", "Access-Control-Request-Method": "PUT"},
+			expectedStatus: http.StatusNoContent,
+			expectedHeaders: map[string]string{
+				"Access-Control-Allow-Origin":      "http://localhost:8080",
+				"Access-Control-Allow-Methods":     "GET, HEAD, POST, PUT, PATCH, DELETE",
+				"Access-Control-Allow-Headers":     "Content-Type, Authorization",
+				"Access-Control-Allow-Credentials": "true",
+				"Access-Control-Expose-Headers":    "X-Total-Count",
+				"Access-Control-Max-Age":           "600",
+			},
+		},
+		{
+			method:         "GET",
+			requestPath:    "/test",
+			headers:        map[string]string{"Origin": "https://app.example.com"},
+			expectedStatus: http.StatusOK,
+			expectedHeaders: map[string]string{
+				"Access-Control-Allow-Origin":      "https://app.example.com",
+				"Access-Control-Allow-Methods":     "",
+				"Access-Control-Allow-Credentials": "true",
+				"Access-Control-Expose-Headers":    "X-Total-Count",
+				"Access-Control-Max-Age":           "",
+				"Vary":                             "Origin",
+			},
+		},
+		{
+			method:         "GET",
+			requestPath:    "/test",
+			headers:        map[string]string{"Origin": "http://evil.com"},
+			expectedStatus: http.StatusOK,
+			expectedHeaders: map[string]string{
+				"Access-Control-Allow-Origin":      "",
+				"Access-Control-Allow-Credentials": "",
+			},
+		},
+		{
+			method:         "OPTIONS",
+			requestPath:    "/public",
+			headers:        map[string]string{"Origin": "http://evil.com", "Access-Control-Request-Headers": "X-Custom"},
+			expectedStatus: http.StatusNoContent,
+			expectedHeaders: map[string]string{
+				"Access-Control-Allow-Origin":      "http://evil.com",
+				"Access-Control-Allow-Methods":     "GET",
+				"Access-Control-Allow-Headers":     "X-Custom",
+				"Access-Control-Allow-Credentials": "",
+				"Access-Control-Max-Age":           "600",
+			},
+		},
+	}
+	for i, test := range tests {
+		req, err := http.NewRequest(test.method, test.requestPath, nil)
+		if err != nil {
+			t.Fatalf("An error occured when creating the request: %v for test %d", err, i)
+		}
+		for name, value := range test.headers {
+			req.Header.Set(name, value)
+		}
+		rec := httptest.NewRecorder()
+
+		handler.ServeHTTP(rec, req)
+		if rec.Code != test.expectedStatus {
+			t.Fatalf("Test %d, expected status: %d, got %d", i, test.expectedStatus, rec.Code)
+		}
+		for name, expected := range test.expectedHeaders {
+			if value := rec.Header().Get(name); value != expected {
+				t.Fatalf("Test %d, expected header %s: %s, got %s", i, name, expected, value)
+			}
+		}
+	}
+}
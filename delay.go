@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	delayQuery  = "_delay"
+	delayHeader = "X-Iseva-Delay"
+)
+
+// delayConfig is the time to wait before answering, in milliseconds. It is
+// either a number, for a fixed delay, or an object picking the delay from
+// a distribution:
+//
+//	{"min": 100, "max": 500} is uniform between min and max
+//	{"mean": 300, "stddev": 50} is normal, never below 0
+//	{"p50": 100, "p95": 800, "p99": 2000} follows the given percentiles
+type delayConfig struct {
+	Fixed  int     `json:"fixed"`
+	Min    int     `json:"min"`
+	Max    int     `json:"max"`
+	Mean   float64 `json:"mean"`
+	StdDev float64 `json:"stddev"`
+	P50    int     `json:"p50"`
+	P90    int     `json:"p90"`
+	P95    int     `json:"p95"`
+	P99    int     `json:"p99"`
+}
+
+func (conf *delayConfig) UnmarshalJSON(data []byte) error {
+	var fixed int
+	if err := json.Unmarshal(data, &fixed); err == nil {
+		*conf = delayConfig{Fixed: fixed}
+		return nil
+	}
+	type plain delayConfig
+	var p plain
+	if err := json.Unmarshal(data, &p); err != nil {
+		return fmt.Errorf("delay must be a number of milliseconds or an object: %v", err)
+	}
+	*conf = delayConfig(p)
+	if conf.Max < conf.Min {
+		return fmt.Errorf("delay: max %d is lower than min %d", conf.Max, conf.Min)
+	}
+	return nil
+}
+
+// duration picks a delay.
+func (conf *delayConfig) duration() time.Duration {
+	if conf == nil {
+		return 0
+	}
+	var ms float64
+	switch {
+	case conf.P50 != 0 || conf.P90 != 0 || conf.P95 != 0 || conf.P99 != 0:
+		ms = conf.percentile(rand.Float64() * 100)
+	case conf.StdDev != 0 || conf.Mean != 0:
+		ms = math.Max(0, conf.Mean+rand.NormFloat64()*conf.StdDev)
+	case conf.Max > conf.Min:
+		ms = float64(conf.Min) + rand.Float64()*float64(conf.Max-conf.Min)
+	case conf.Max != 0:
+		ms = float64(conf.Max)
+	default:
+		ms = float64(conf.Fixed)
+	}
+	return time.Duration(ms * float64(time.Millisecond))
+}
+
+// percentile interpolates linearly the delay of the percentile p between
+// the percentiles given, min and max being the 0th and 100th ones.
+func (conf *delayConfig) percentile(p float64) float64 {
+	type point struct{ p, ms float64 }
+	points := []point{{0, float64(conf.Min)}}
+	for _, pt := range []point{{50, float64(conf.P50)}, {90, float64(conf.P90)}, {95, float64(conf.P95)}, {99, float64(conf.P99)}} {
+		if pt.ms != 0 {
+			points = append(points, pt)
+		}
+	}
+	last := points[len(points)-1].ms
+	if conf.Max != 0 {
+		last = float64(conf.Max)
+	}
+	points = append(points, point{100, last})
+	for i := 1; i < len(points); i++ {
+		if p <= points[i].p {
+			from, to := points[i-1], points[i]
+			return from.ms + (to.ms-from.ms)*(p-from.p)/(to.p-from.p)
+		}
+	}
+	return last
+}
+
+// requestDelay returns the delay asked by the request, in the _delay query
+// parameter or the X-Iseva-Delay header, in milliseconds.
+func requestDelay(r *http.Request) (*delayConfig, error) {
+	value := r.URL.Query().Get(delayQuery)
+	if value == "" {
+		value = r.Header.Get(delayHeader)
+	}
+	if value == "" {
+		return nil, nil
+	}
+	ms, err := strconv.Atoi(value)
+	if err != nil || ms < 0 {
+		return nil, fmt.Errorf("invalid delay %q, expected a number of milliseconds", value)
+	}
+	return &delayConfig{Fixed: ms}, nil
+}
+
+// wait sleeps for the delay, or until the request is canceled.
+func wait(r *http.Request, d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-r.Context().Done():
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDelayConfig(t *testing.T) {
+	tests := []struct {
+		config      string
+		expectedMin time.Duration
+		expectedMax time.Duration
+		expectedErr bool
+	}{
+		{config: `150`, expectedMin: 150 * time.Millisecond, expectedMax: 150 * time.Millisecond},
+		{config: `{"fixed": 30}`, expectedMin: 30 * time.Millisecond, expectedMax: 30 * time.Millisecond},
+		{config: `{"min": 100, "max": 200}`, expectedMin: 100 * time.Millisecond, expectedMax: 200 * time.Millisecond},
+		{config: `{"mean": 100, "stddev": 0.0001}`, expectedMin: 99 * time.Millisecond, expectedMax: 101 * time.Millisecond},
+		{config: `{"mean": -100, "stddev": 1}`, expectedMin: 0, expectedMax: 0},
+		{config: `{"min": 10, "p50": 100, "p99": 1000, "max": 2000}`, expectedMin: 10 * time.Millisecond, expectedMax: 2000 * time.Millisecond},
+		{config: `{"min": 200, "max": 100}`, expectedErr: true},
+		{config: `"slow"`, expectedErr: true},
+	}
+	for i, test := range tests {
+		var conf delayConfig
+		err := json.Unmarshal([]byte(test.config), &conf)
+		if (err != nil) != test.expectedErr {
+			t.Fatalf("Test %d: expected error %t, got %v", i, test.expectedErr, err)
+		}
+		if err != nil {
+			continue
+		}
+		for n := 0; n < 100; n++ {
+			d := conf.duration()
+			if d < test.expectedMin || d > test.expectedMax {
+				t.Fatalf("Test %d: the delay %v is outside of the boundaries Min: %v, Max: %v", i, d, test.expectedMin, test.expectedMax)
+			}
+		}
+	}
+}
+
+func TestDelayPercentile(t *testing.T) {
+	conf := delayConfig{P50: 100, P95: 1000}
+	tests := []struct {
+		p        float64
+		expected float64
+	}{
+		{p: 0, expected: 0},
+		{p: 25, expected: 50},
+		{p: 50, expected: 100},
+		{p: 95, expected: 1000},
+		{p: 100, expected: 1000},
+	}
+	for i, test := range tests {
+		if ms := conf.percentile(test.p); ms != test.expected {
+			t.Fatalf("Test %d: expected %v for the percentile %v, got %v", i, test.expected, test.p, ms)
+		}
+	}
+}
+
+func TestDelay(t *testing.T) {
+	handler, err := NewJSONHandler("testdata/db_delay.json", true)
+	if err != nil {
+		t.Fatalf("An error occured when loading the file: %v", err)
+	}
+	// only the lower bounds are checked, as a busy machine can always be
+	// slower
+	tests := []struct {
+		requestPath    string
+		headers        map[string]string
+		expectedStatus int
+		expectedMin    time.Duration
+	}{
+		{requestPath: "/test", expectedStatus: http.StatusOK, expectedMin: 20 * time.Millisecond},
+		{requestPath: "/slow", expectedStatus: http.StatusOK, expectedMin: 60 * time.Millisecond},
+		{requestPath: "/slow?_delay=0", expectedStatus: http.StatusOK},
+		{requestPath: "/test", headers: map[string]string{"X-Iseva-Delay": "100"}, expectedStatus: http.StatusOK, expectedMin: 100 * time.Millisecond},
+		{requestPath: "/test?_delay=soon", expectedStatus: http.StatusBadRequest},
+	}
+	for i, test := range tests {
+		req, err := http.NewRequest("GET", test.requestPath, nil)
+		if err != nil {
+			t.Fatalf("An error occured when creating the request: %v for test %d", err, i)
+		}
+		for name, value := range test.headers {
+			req.Header.Set(name, value)
+		}
+		rec := httptest.NewRecorder()
+
+		start := time.Now()
+		handler.ServeHTTP(rec, req)
+		elapsed := time.Since(start)
+		if rec.Code != test.expectedStatus {
+			t.Fatalf("Test %d, expected status: %d, got %d", i, test.expectedStatus, rec.Code)
+		}
+		if elapsed < test.expectedMin {
+			t.Fatalf("Test %d, the answer took %v, expected at least %v", i, elapsed, test.expectedMin)
+		}
+	}
+}
+
+func TestRequestDelay(t *testing.T) {
+	tests := []struct {
+		requestPath   string
+		headers       map[string]string
+		expectedDelay *time.Duration
+		expectedErr   bool
+	}{
+		{requestPath: "/slow"},
+		{requestPath: "/slow?_delay=0", expectedDelay: durationOf(0)},
+		{requestPath: "/slow?_delay=25", headers: map[string]string{"X-Iseva-Delay": "100"}, expectedDelay: durationOf(25 * time.Millisecond)},
+		{requestPath: "/slow", headers: map[string]string{"X-Iseva-Delay": "100"}, expectedDelay: durationOf(100 * time.Millisecond)},
+		{requestPath: "/slow?_delay=soon", expectedErr: true},
+		{requestPath: "/slow?_delay=-1", expectedErr: true},
+	}
+	for i, test := range tests {
+		req, err := http.NewRequest("GET", test.requestPath, nil)
+		if err != nil {
+			t.Fatalf("An error occured when creating the request: %v for test %d", err, i)
+		}
+		for name, value := range test.headers {
+			req.Header.Set(name, value)
+		}
+		conf, err := requestDelay(req)
+		if (err != nil) != test.expectedErr {
+			t.Fatalf("Test %d: expected error %t, got %v", i, test.expectedErr, err)
+		}
+		if (conf == nil) != (test.expectedDelay == nil) {
+			t.Fatalf("Test %d: expected a delay %t, got %v", i, test.expectedDelay != nil, conf)
+		}
+		if conf != nil && conf.duration() != *test.expectedDelay {
+			t.Fatalf("Test %d: expected the delay %v, got %v", i, *test.expectedDelay, conf.duration())
+		}
+	}
+}
+
+func durationOf(d time.Duration) *time.Duration {
+	return &d
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	urlSection      = "url part"
+	templateSection = "template part"
+)
+
+// loadError tells where the db file is broken: the section, the position
+// in it and the route being read when it is known, with the text around.
+type loadError struct {
+	File    string
+	Section string
+	Line    int
+	Column  int
+	Route   string
+	Snippet string
+	Err     error
+}
+
+func (e *loadError) Error() string {
+	var buf bytes.Buffer
+	buf.WriteString(e.File)
+	if e.Section != "" {
+		buf.WriteString(": " + e.Section)
+	}
+	if e.Line > 0 {
+		fmt.Fprintf(&buf, ", line %d", e.Line)
+	}
+	if e.Column > 0 {
+		fmt.Fprintf(&buf, ", column %d", e.Column)
+	}
+	if e.Route != "" {
+		fmt.Fprintf(&buf, ", route %q", e.Route)
+	}
+	fmt.Fprintf(&buf, ": %v", e.Err)
+	if e.Snippet != "" {
+		buf.WriteString("\n" + e.Snippet)
+	}
+	return buf.String()
+}
+
+// jsonError locates the error returned when decoding the text of a
+// section. firstLine is the line of the file the section starts on.
+func jsonError(file, section, text string, firstLine int, err error) *loadError {
+	le := &loadError{File: file, Section: section, Err: err}
+	var offset int64
+	switch e := err.(type) {
+	case *json.SyntaxError:
+		offset = e.Offset
+	case *json.UnmarshalTypeError:
+		offset = e.Offset
+	}
+	if offset > 0 && int(offset) <= len(text) {
+		le.Route = routeOf(keyPath([]byte(text), int(offset)))
+		le.at(text, int(offset)-1, firstLine)
+	}
+	return le
+}
+
+// urlsError locates the error returned when decoding the rendered url
+// part. The errors returned by a route can't be located from their
+// offset, so the routes are decoded one by one to find the broken one.
+func urlsError(file, text string, err error) *loadError {
+	var urls struct {
+		URLs map[string]json.RawMessage `json:"urls"`
+	}
+	if json.Unmarshal([]byte(text), &urls) != nil {
+		return jsonError(file, urlSection, text, 1, err)
+	}
+	keys := make([]string, 0, len(urls.URLs))
+	for key := range urls.URLs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		var rt route
+		if routeErr := json.Unmarshal(urls.URLs[key], &rt); routeErr != nil {
+			le := &loadError{File: file, Section: urlSection, Route: key, Err: routeErr}
+			quoted, _ := json.Marshal(key)
+			if offset := strings.Index(text, string(quoted)); offset >= 0 {
+				le.at(text, offset, 1)
+			}
+			return le
+		}
+	}
+	return jsonError(file, urlSection, text, 1, err)
+}
+
+var templateErrorRegexp = regexp.MustCompile(`^template: [^:]*:(\d+)(?::(\d+))?: (?:executing "[^"]*" at <[^>]*>: )?`)
+
+// templateError locates an error returned when parsing or executing the
+// url part, as the template package writes its position in the message.
+func templateError(file, text string, err error) *loadError {
+	le := &loadError{File: file, Section: urlSection, Err: err}
+	match := templateErrorRegexp.FindStringSubmatch(err.Error())
+	if match == nil {
+		return le
+	}
+	le.Line, _ = strconv.Atoi(match[1])
+	le.Column, _ = strconv.Atoi(match[2])
+	le.Err = fmt.Errorf("%s", err.Error()[len(match[0]):])
+	le.Snippet = snippet(text, le.Line, le.Column)
+	return le
+}
+
+// formatLoadError locates a syntax error found when turning a section
+// written in YAML, TOML or JSON5 into JSON.
+func formatLoadError(file, section, text string, firstLine int, err error) *loadError {
+	le := &loadError{File: file, Section: section, Err: err}
+	if fe, ok := err.(*formatError); ok {
+		le.Err = fmt.Errorf("%s", fe.Msg)
+		le.Line = fe.Line + firstLine - 1
+		le.Column = fe.Column
+		le.Snippet = snippet(text, fe.Line, fe.Column)
+	}
+	return le
+}
+
+// located removes the position of an error found in the JSON made from a
+// format that doesn't keep the lines of the file, as it would point to
+// the JSON. The route is kept.
+func (le *loadError) located(format fileFormat) *loadError {
+	if !format.keepsLines() {
+		le.Line, le.Column, le.Snippet = 0, 0, ""
+	}
+	return le
+}
+
+// at sets the position of the error to the byte at offset in the text of
+// the section.
+func (le *loadError) at(text string, offset, firstLine int) {
+	line, column := position(text, offset)
+	le.Snippet = snippet(text, line, column)
+	le.Line = line + firstLine - 1
+	le.Column = column
+}
+
+// position returns the line and the column, counted from 1, of the byte at
+// offset in text.
+func position(text string, offset int) (int, int) {
+	if offset > len(text) {
+		offset = len(text)
+	}
+	before := text[:offset]
+	line := strings.Count(before, "\n") + 1
+	column := offset - strings.LastIndex(before, "\n")
+	return line, column
+}
+
+// snippet returns the given line of the text, with a caret under the
+// column if it is known.
+func snippet(text string, line, column int) string {
+	lines := strings.Split(text, "\n")
+	if line < 1 || line > len(lines) {
+		return ""
+	}
+	content := strings.TrimRight(lines[line-1], "\r")
+	if column < 1 || column > len(content)+1 {
+		return "    " + content
+	}
+	caret := strings.Map(func(r rune) rune {
+		if r == '\t' {
+			return r
+		}
+		return ' '
+	}, content[:column-1])
+	return "    " + content + "\n    " + caret + "^"
+}
+
+// keyPath returns the keys of the objects enclosing the byte at offset.
+func keyPath(data []byte, offset int) []string {
+	type frame struct {
+		object    bool
+		expectKey bool
+		key       string
+	}
+	var stack []frame
+	dec := json.NewDecoder(bytes.NewReader(data[:offset]))
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			break
+		}
+		top := len(stack) - 1
+		if delim, ok := tok.(json.Delim); ok && (delim == '}' || delim == ']') {
+			stack = stack[:top]
+			if top > 0 && stack[top-1].object {
+				stack[top-1].expectKey = true
+			}
+			continue
+		}
+		if top >= 0 && stack[top].object && stack[top].expectKey {
+			stack[top].key, _ = tok.(string)
+			stack[top].expectKey = false
+			continue
+		}
+		if delim, ok := tok.(json.Delim); ok {
+			stack = append(stack, frame{object: delim == '{', expectKey: delim == '{'})
+			continue
+		}
+		if top >= 0 && stack[top].object {
+			stack[top].expectKey = true
+		}
+	}
+	var path []string
+	for _, f := range stack {
+		if f.object && f.key != "" {
+			path = append(path, f.key)
+		}
+	}
+	return path
+}
+
+// routeOf returns the route of a key path in the url part.
+func routeOf(path []string) string {
+	if len(path) > 1 && path[0] == "urls" {
+		return path[1]
+	}
+	return ""
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLoadError(t *testing.T) {
+	tests := []struct {
+		db              string
+		expectedSection string
+		expectedLine    int
+		expectedColumn  int
+		expectedRoute   string
+		expectedSnippet string
+	}{
+		{
+			db:              "testdata/db_first_part_broken.json",
+			expectedSection: urlSection,
+			expectedLine:    8,
+			expectedColumn:  1,
+			expectedRoute:   "/test/other",
+			expectedSnippet: "}",
+		},
+		{
+			db:              "testdata/db_second_part_broken.json",
+			expectedSection: templateSection,
+			expectedLine:    5,
+			expectedColumn:  2,
+			expectedSnippet: "{",
+		},
+		{
+			db:              "testdata/db_template_broken.json",
+			expectedSection: urlSection,
+			expectedLine:    3,
+			expectedSnippet: `"json": {"key":"{{}"}`,
+		},
+		{
+			db:              "testdata/db_route_broken.json",
+			expectedSection: urlSection,
+			expectedLine:    6,
+			expectedColumn:  9,
+			expectedRoute:   "/test/status",
+			expectedSnippet: `"/test/status": {`,
+		},
+		{
+			db:              "testdata/db_execute_broken.json",
+			expectedSection: urlSection,
+			expectedLine:    4,
+			expectedColumn:  34,
+			expectedSnippet: `"json": {"key": {{.var.field}}}`,
+		},
+		{
+			db:              "testdata/db_yaml_broken.yaml",
+			expectedSection: templateSection,
+			expectedLine:    6,
+			expectedSnippet: "var: [1, 2",
+		},
+		{
+			db:              "testdata/db_two_separators.json",
+			expectedSection: templateSection,
+			expectedLine:    7,
+			expectedSnippet: "---",
+		},
+		{
+			db:              "testdata/db_reserved_variable.json",
+			expectedSection: templateSection,
+		},
+	}
+	for i, test := range tests {
+		handler := JSONHandler{DB: test.db}
+		err := handler.getDBData()
+		le, ok := err.(*loadError)
+		if !ok {
+			t.Fatalf("Test %d: expected a load error, got %T: %v", i, err, err)
+		}
+		if le.Section != test.expectedSection {
+			t.Fatalf("Test %d: expected section %s, got %s", i, test.expectedSection, le.Section)
+		}
+		if le.Line != test.expectedLine || le.Column != test.expectedColumn {
+			t.Fatalf("Test %d: expected line %d column %d, got line %d column %d", i, test.expectedLine, test.expectedColumn, le.Line, le.Column)
+		}
+		if le.Route != test.expectedRoute {
+			t.Fatalf("Test %d: expected route %s, got %s", i, test.expectedRoute, le.Route)
+		}
+		if !strings.Contains(le.Snippet, test.expectedSnippet) {
+			t.Fatalf("Test %d: expected the snippet to contain %s, got %s", i, test.expectedSnippet, le.Snippet)
+		}
+		if !strings.HasPrefix(le.Error(), test.db+": "+test.expectedSection) {
+			t.Fatalf("Test %d: expected the message to start with the file and the section, got %s", i, le.Error())
+		}
+	}
+}
+
+func TestKeyPath(t *testing.T) {
+	data := []byte(`{"urls": {"/a": {"json": [1, 2]}, "/b": {"json": {"key": tru`)
+	path := keyPath(data, len(data))
+	expected := []string{"urls", "/b", "json", "key"}
+	if strings.Join(path, " ") != strings.Join(expected, " ") {
+		t.Fatalf("Expected path %v, got %v", expected, path)
+	}
+}
+
+func TestErrorBody(t *testing.T) {
+	handler := JSONHandler{DB: "testdata/db_first_part_broken.json"}
+	req, err := http.NewRequest("GET", "/test", nil)
+	if err != nil {
+		t.Fatalf("An error occured when creating the request: %v", err)
+	}
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("Expected status: %d, got %d", http.StatusInternalServerError, rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), `route \"/test/other\"`) {
+		t.Fatalf("Expected the body to contain the error, got %s", rec.Body.String())
+	}
+}
+
+func TestSeveralFilesError(t *testing.T) {
+	tests := []struct {
+		db              string
+		expectedFile    string
+		expectedSection string
+		expectedRoute   string
+		expectedMessage string
+	}{
+		{
+			db:              "testdata/multi_duplicate",
+			expectedFile:    "testdata/multi_duplicate/second.json",
+			expectedSection: urlSection,
+			expectedRoute:   "/users",
+			expectedMessage: "route already defined in testdata/multi_duplicate/first.json",
+		},
+		{
+			db:              "testdata/multi/orders.yaml",
+			expectedFile:    "testdata/multi/orders.yaml",
+			expectedSection: urlSection,
+			expectedMessage: `function "score" not defined`,
+		},
+	}
+	for i, test := range tests {
+		handler := JSONHandler{DB: test.db}
+		err := handler.getDBData()
+		le, ok := err.(*loadError)
+		if !ok {
+			t.Fatalf("Test %d: expected a load error, got %T: %v", i, err, err)
+		}
+		if le.File != test.expectedFile || le.Section != test.expectedSection || le.Route != test.expectedRoute {
+			t.Fatalf("Test %d: expected file %s, section %s and route %s, got %s, %s and %s", i,
+				test.expectedFile, test.expectedSection, test.expectedRoute, le.File, le.Section, le.Route)
+		}
+		if !strings.Contains(le.Err.Error(), test.expectedMessage) {
+			t.Fatalf("Test %d: expected the message to contain %s, got %v", i, test.expectedMessage, le.Err)
+		}
+	}
+}
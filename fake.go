@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+var (
+	firstNames = []string{
+		"James", "Mary", "John", "Patricia", "Robert", "Jennifer", "Michael", "Linda",
+		"William", "Elizabeth", "David", "Barbara", "Richard", "Susan", "Joseph", "Jessica",
+		"Thomas", "Sarah", "Charles", "Karen", "Daniel", "Nancy", "Matthew", "Lisa",
+		"Anthony", "Betty", "Mark", "Sandra", "Paul", "Ashley", "Steven", "Emily",
+		"Andrew", "Donna", "Joshua", "Michelle", "Kevin", "Carol", "Brian", "Amanda",
+		"Maxime", "Camille", "Louis", "Chloe", "Hugo", "Lea", "Lucas", "Manon",
+	}
+	lastNames = []string{
+		"Smith", "Johnson", "Williams", "Brown", "Jones", "Garcia", "Miller", "Davis",
+		"Rodriguez", "Martinez", "Hernandez", "Lopez", "Gonzalez", "Wilson", "Anderson", "Thomas",
+		"Taylor", "Moore", "Jackson", "Martin", "Lee", "Perez", "Thompson", "White",
+		"Harris", "Sanchez", "Clark", "Ramirez", "Lewis", "Robinson", "Walker", "Young",
+		"Allen", "King", "Wright", "Scott", "Torres", "Nguyen", "Hill", "Flores",
+		"Dubois", "Bernard", "Durand", "Lefebvre", "Moreau", "Laurent", "Simon", "Michel",
+	}
+	streetNames = []string{
+		"Main", "Oak", "Pine", "Maple", "Cedar", "Elm", "Washington", "Lake",
+		"Hill", "Park", "Sunset", "Church", "Mill", "River", "Spring", "Highland",
+	}
+	streetSuffixes = []string{"Street", "Avenue", "Road", "Boulevard", "Lane", "Drive", "Court", "Way"}
+	cities         = []string{
+		"Springfield", "Riverside", "Fairview", "Franklin", "Greenville", "Bristol", "Clinton", "Salem",
+		"Madison", "Georgetown", "Arlington", "Ashland", "Dover", "Oxford", "Milton", "Newport",
+	}
+	domains     = []string{"example.com", "example.org", "example.net", "mail.test", "inbox.test"}
+	tlds        = []string{"com", "org", "net", "io", "dev", "test"}
+	loremWords  = strings.Fields("lorem ipsum dolor sit amet consectetur adipiscing elit sed do eiusmod tempor incididunt ut labore et dolore magna aliqua enim ad minim veniam quis nostrud exercitation ullamco laboris nisi aliquip ex ea commodo consequat duis aute irure in reprehenderit voluptate velit esse cillum fugiat nulla pariatur excepteur sint occaecat cupidatat non proident sunt culpa qui officia deserunt mollit anim id est laborum")
+	defaultFrom = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	defaultTo   = time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// fakers are the generators of realistic values, used as types of the
+// random and array functions. The strings are written without quotes, like
+// the ones of the string type.
+var fakers = map[string]func(rnd *rand.Rand, p random) interface{}{
+	"firstname": func(rnd *rand.Rand, p random) interface{} { return pick(rnd, firstNames) },
+	"lastname":  func(rnd *rand.Rand, p random) interface{} { return pick(rnd, lastNames) },
+	"name": func(rnd *rand.Rand, p random) interface{} {
+		return pick(rnd, firstNames) + " " + pick(rnd, lastNames)
+	},
+	"email": func(rnd *rand.Rand, p random) interface{} {
+		return strings.ToLower(pick(rnd, firstNames)+"."+pick(rnd, lastNames)) + "@" + pick(rnd, domains)
+	},
+	"uuid": func(rnd *rand.Rand, p random) interface{} {
+		b := make([]byte, 16)
+		for i := range b {
+			b[i] = byte(rnd.Intn(256))
+		}
+		b[6] = b[6]&0x0f | 0x40 // version 4
+		b[8] = b[8]&0x3f | 0x80 // variant 10
+		return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+	},
+	"date": func(rnd *rand.Rand, p random) interface{} {
+		// counted in seconds, as a range of nanoseconds overflows after
+		// 292 years
+		from, to := p.dateRange()
+		seconds := from.Unix() + rnd.Int63n(to.Unix()-from.Unix()+1)
+		return time.Unix(seconds, 0).In(from.Location()).Format(time.RFC3339)
+	},
+	"phone": func(rnd *rand.Rand, p random) interface{} {
+		return fmt.Sprintf("+1-%03d-%03d-%04d", 200+rnd.Intn(800), 200+rnd.Intn(800), rnd.Intn(10000))
+	},
+	"address": func(rnd *rand.Rand, p random) interface{} {
+		return fmt.Sprintf("%d %s %s, %s", 1+rnd.Intn(9999), pick(rnd, streetNames), pick(rnd, streetSuffixes), pick(rnd, cities))
+	},
+	"city": func(rnd *rand.Rand, p random) interface{} { return pick(rnd, cities) },
+	"word": func(rnd *rand.Rand, p random) interface{} { return pick(rnd, loremWords) },
+	"sentence": func(rnd *rand.Rand, p random) interface{} {
+		return sentence(rnd, p.count(rnd, 4, 12))
+	},
+	"paragraph": func(rnd *rand.Rand, p random) interface{} {
+		sentences := make([]string, p.count(rnd, 3, 6))
+		for i := range sentences {
+			sentences[i] = sentence(rnd, 4+rnd.Intn(9))
+		}
+		return strings.Join(sentences, " ")
+	},
+	"url": func(rnd *rand.Rand, p random) interface{} {
+		return fmt.Sprintf("https://www.%s%s.%s/%s", pick(rnd, loremWords), pick(rnd, loremWords), pick(rnd, tlds), pick(rnd, loremWords))
+	},
+	"ipv4": func(rnd *rand.Rand, p random) interface{} {
+		return fmt.Sprintf("%d.%d.%d.%d", 1+rnd.Intn(254), rnd.Intn(256), rnd.Intn(256), 1+rnd.Intn(254))
+	},
+	"ipv6": func(rnd *rand.Rand, p random) interface{} {
+		groups := make([]string, 8)
+		for i := range groups {
+			groups[i] = fmt.Sprintf("%x", rnd.Intn(0x10000))
+		}
+		return strings.Join(groups, ":")
+	},
+	"bool":  func(rnd *rand.Rand, p random) interface{} { return rnd.Intn(2) == 1 },
+	"color": func(rnd *rand.Rand, p random) interface{} { return fmt.Sprintf("#%06x", rnd.Intn(0x1000000)) },
+}
+
+func pick(rnd *rand.Rand, list []string) string {
+	return list[rnd.Intn(len(list))]
+}
+
+// sentence returns n lorem ipsum words, capitalized and ending with a dot.
+func sentence(rnd *rand.Rand, n int) string {
+	words := make([]string, n)
+	for i := range words {
+		words[i] = pick(rnd, loremWords)
+	}
+	s := strings.Join(words, " ")
+	return strings.ToUpper(s[:1]) + s[1:] + "."
+}
+
+// count returns the number of elements of a generated value: size if
+// given, else between min and max, else between the given defaults.
+func (p random) count(rnd *rand.Rand, defaultMin, defaultMax int) int {
+	switch {
+	case p.Size > 0:
+		return p.Size
+	case p.Max > p.Min && p.Min > 0:
+		return p.Min + rnd.Intn(p.Max-p.Min+1)
+	}
+	return defaultMin + rnd.Intn(defaultMax-defaultMin+1)
+}
+
+// dateRange returns the dates between which the date type picks, from
+// 2000 to 2030 by default.
+func (p random) dateRange() (time.Time, time.Time) {
+	from, to := defaultFrom, defaultTo
+	if t, err := parseDate(p.From); err == nil {
+		from = t
+	}
+	if t, err := parseDate(p.To); err == nil {
+		to = t
+	}
+	if to.Before(from) {
+		return to, from
+	}
+	return from, to
+}
+
+// parseDate reads a date written as in RFC 3339, with or without the time.
+func parseDate(value string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
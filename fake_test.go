@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestFakers(t *testing.T) {
+	tests := []struct {
+		param    random
+		expected string
+	}{
+		{param: random{Type: "firstname"}, expected: `^[A-Z][a-z]+$`},
+		{param: random{Type: "lastname"}, expected: `^[A-Z][a-z]+$`},
+		{param: random{Type: "name"}, expected: `^[A-Z][a-z]+ [A-Z][a-z]+$`},
+		{param: random{Type: "email"}, expected: `^[a-z]+\.[a-z]+@[a-z]+\.[a-z]+$`},
+		{param: random{Type: "uuid"}, expected: `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`},
+		{param: random{Type: "date"}, expected: `^20[0-3]\d-\d\d-\d\dT\d\d:\d\d:\d\dZ$`},
+		{param: random{Type: "date", From: "2016-03-01", To: "2016-03-02"}, expected: `^2016-03-0[12]T`},
+		{param: random{Type: "date", From: "0001-01-01", To: "9999-12-31"}, expected: `^\d{4}-\d\d-\d\dT\d\d:\d\d:\d\dZ$`},
+		{param: random{Type: "phone"}, expected: `^\+1-\d{3}-\d{3}-\d{4}$`},
+		{param: random{Type: "address"}, expected: `^\d+ [A-Z][a-z]+ [A-Z][a-z]+, [A-Z][a-z]+$`},
+		{param: random{Type: "city"}, expected: `^[A-Z][a-z]+$`},
+		{param: random{Type: "word"}, expected: `^[a-z]+$`},
+		{param: random{Type: "sentence", Size: 3}, expected: `^[A-Z][a-z]* [a-z]+ [a-z]+\.$`},
+		{param: random{Type: "paragraph"}, expected: `^([A-Z][a-z ]+\. ?){3,6}$`},
+		{param: random{Type: "url"}, expected: `^https://www\.[a-z]+\.[a-z]+/[a-z]+$`},
+		{param: random{Type: "ipv4"}, expected: `^\d{1,3}\.\d{1,3}\.\d{1,3}\.\d{1,3}$`},
+		{param: random{Type: "ipv6"}, expected: `^[0-9a-f]{1,4}(:[0-9a-f]{1,4}){7}$`},
+		{param: random{Type: "bool"}, expected: `^(true|false)$`},
+		{param: random{Type: "color"}, expected: `^#[0-9a-f]{6}$`},
+	}
+	for i, test := range tests {
+		params := funcParams{Randoms: map[string]random{"fake": test.param}}
+		functions := params.parse()
+		f, ok := functions["fake"].(func() string)
+		if !ok {
+			t.Fatalf("Test %d: error when parsing the function %s, the actual type is %T", i, test.param.Type, functions["fake"])
+		}
+		re := regexp.MustCompile(test.expected)
+		for n := 0; n < 20; n++ {
+			if result := f(); !re.MatchString(result) {
+				t.Fatalf("Test %d: the %s %s doesn't match %s", i, test.param.Type, result, test.expected)
+			}
+		}
+	}
+}
+
+func TestFakerArray(t *testing.T) {
+	params := funcParams{
+		Arrays: map[string]array{
+			"dates": array{Type: "date", ArraySize: 10, From: "2016-01-01T00:00:00Z", To: "2016-12-31T00:00:00Z"},
+			"bools": array{Type: "bool", ArraySize: 4},
+		},
+	}
+	functions := params.parse()
+	result, err := functions["dates"].(func() (string, error))()
+	if err != nil {
+		t.Fatalf("An error occured when execution the function: %v", err)
+	}
+	var dates []time.Time
+	if err := json.Unmarshal([]byte(result), &dates); err != nil {
+		t.Fatalf("Error while unmarshalling the result: %s, the error was: %v", result, err)
+	}
+	if len(dates) != 10 {
+		t.Fatalf("Expected 10 dates, got %d", len(dates))
+	}
+	for _, date := range dates {
+		if date.Year() != 2016 {
+			t.Fatalf("The date %v is outside of 2016", date)
+		}
+	}
+	result, err = functions["bools"].(func() (string, error))()
+	if err != nil {
+		t.Fatalf("An error occured when execution the function: %v", err)
+	}
+	var bools []bool
+	if err := json.Unmarshal([]byte(result), &bools); err != nil {
+		t.Fatalf("Error while unmarshalling the result: %s, the error was: %v", result, err)
+	}
+}
@@ -0,0 +1,400 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// includeKey is the key of the template part listing the files to load
+// with the file.
+const includeKey = "$include"
+
+// sourceFile is one of the files of the db. Its url part, when it has one,
+// is rendered with the variables and functions of all the files.
+type sourceFile struct {
+	path    string
+	source  string
+	format  fileFormat
+	tmpl    *template.Template
+	modTime time.Time
+	size    int64
+}
+
+// dbFiles returns the files given by db: the file itself, the files of a
+// directory with a known extension, or the files matching a pattern like
+// mocks/*.json. They are sorted by name.
+func dbFiles(db string) ([]string, error) {
+	if strings.ContainsAny(db, "*?[") {
+		matches, err := filepath.Glob(db)
+		if err != nil {
+			return nil, err
+		}
+		var files []string
+		for _, match := range matches {
+			if info, err := os.Stat(match); err == nil && !info.IsDir() {
+				files = append(files, match)
+			}
+		}
+		if len(files) == 0 {
+			return nil, fmt.Errorf("no file matches %s", db)
+		}
+		return files, nil
+	}
+	info, err := os.Stat(db)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{db}, nil
+	}
+	entries, err := ioutil.ReadDir(db)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, entry := range entries {
+		if !entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") && knownExtension(entry.Name()) {
+			files = append(files, filepath.Join(db, entry.Name()))
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no db file in the directory %s", db)
+	}
+	return files, nil
+}
+
+// dbFiles returns the files of the db, leaving out the state file which
+// can be in the same directory.
+func (handler *JSONHandler) dbFiles() ([]string, error) {
+	files, err := dbFiles(handler.DB)
+	if err != nil || handler.State == "" {
+		return files, err
+	}
+	kept := files[:0]
+	for _, file := range files {
+		if filepath.Clean(file) != filepath.Clean(handler.State) {
+			kept = append(kept, file)
+		}
+	}
+	return kept, nil
+}
+
+// stamp identifies the version of the files loaded in the content.
+func (c *content) stamp() string {
+	stamps := make(map[string]string, len(c.files))
+	for _, f := range c.files {
+		stamps[filepath.Clean(f.path)] = fmt.Sprintf("%d %d", f.modTime.UnixNano(), f.size)
+	}
+	return joinStamps(stamps)
+}
+
+// stamp identifies the version of the files on disk: the ones of the db
+// and the ones included by the content.
+func (handler *JSONHandler) stamp(c *content) (string, error) {
+	paths, err := handler.dbFiles()
+	if err != nil {
+		return "", err
+	}
+	for _, f := range c.files {
+		paths = append(paths, f.path)
+	}
+	stamps := make(map[string]string, len(paths))
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			stamps[filepath.Clean(path)] = "missing"
+			continue
+		}
+		stamps[filepath.Clean(path)] = fmt.Sprintf("%d %d", info.ModTime().UnixNano(), info.Size())
+	}
+	return joinStamps(stamps), nil
+}
+
+func joinStamps(stamps map[string]string) string {
+	var buf bytes.Buffer
+	for _, path := range sortedKeys(stamps) {
+		fmt.Fprintf(&buf, "%s %s\n", path, stamps[path])
+	}
+	return buf.String()
+}
+
+// loader reads the db files and the ones they include, merging their
+// template parts.
+type loader struct {
+	separator string
+	files     []*sourceFile
+	params    parameters
+	owners    map[string]string
+	seen      map[string]bool
+}
+
+func newLoader(separator string) *loader {
+	return &loader{
+		separator: separator,
+		owners:    make(map[string]string),
+		seen:      make(map[string]bool),
+	}
+}
+
+// read reads the file at path, then the files it includes. A file already
+// read is skipped.
+func (l *loader) read(path string) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	if l.seen[abs] {
+		return nil
+	}
+	l.seen[abs] = true
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	body, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	text := strings.TrimPrefix(string(body), "\ufeff")
+	format := detectFormat(path, text)
+	if format == yamlFormat {
+		text = skipDocumentStart(text)
+	}
+	doc, err := splitDocument(text, l.separator)
+	if err != nil {
+		return formatLoadError(path, templateSection, text, 1, err)
+	}
+	if doc.tmplLine == 0 && onlyParameters(format, doc.urlPart) {
+		doc = document{tmplPart: doc.urlPart, tmplLine: 1}
+	}
+	l.files = append(l.files, &sourceFile{
+		path:    path,
+		source:  doc.urlPart,
+		format:  format,
+		modTime: info.ModTime(),
+		size:    info.Size(),
+	})
+	if strings.TrimSpace(doc.tmplPart) == "" {
+		return nil
+	}
+	tmplJSON, err := format.toJSON(doc.tmplPart)
+	if err != nil {
+		return formatLoadError(path, templateSection, doc.tmplPart, doc.tmplLine, err)
+	}
+	var params parameters
+	if err := json.Unmarshal([]byte(tmplJSON), &params); err != nil {
+		return jsonError(path, templateSection, tmplJSON, doc.tmplLine, err).located(format)
+	}
+	if err := checkReserved(params.Variables); err != nil {
+		return &loadError{File: path, Section: templateSection, Err: err}
+	}
+	if err := l.params.merge(params, path, l.owners); err != nil {
+		return &loadError{File: path, Section: templateSection, Err: err}
+	}
+	includes, err := params.includes()
+	if err != nil {
+		return &loadError{File: path, Section: templateSection, Err: err}
+	}
+	for _, include := range includes {
+		if !filepath.IsAbs(include) {
+			include = filepath.Join(filepath.Dir(path), include)
+		}
+		paths, err := dbFiles(include)
+		if err != nil {
+			return &loadError{File: path, Section: templateSection, Err: fmt.Errorf("%s: %v", includeKey, err)}
+		}
+		for _, included := range paths {
+			if err := l.read(included); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// functionsError reports the invalid functions in the file defining them,
+// the first one read when they are defined in several files.
+func (l *loader) functionsError(err error) error {
+	errs, ok := err.(functionErrors)
+	if !ok {
+		return err
+	}
+	byFile := make(map[string]functionErrors)
+	for _, fe := range errs {
+		path := l.owners["function "+fe.Name]
+		byFile[path] = append(byFile[path], fe)
+	}
+	for _, f := range l.files {
+		if fileErrs, ok := byFile[f.path]; ok {
+			return &loadError{File: f.path, Section: templateSection, Err: fileErrs}
+		}
+	}
+	return err
+}
+
+// onlyParameters tells if a file without separator holds a template part
+// only: variables, functions, a seed or includes.
+func onlyParameters(format fileFormat, text string) bool {
+	converted, err := format.toJSON(text)
+	if err != nil {
+		return false
+	}
+	var sections map[string]json.RawMessage
+	if json.Unmarshal([]byte(converted), &sections) != nil || len(sections) == 0 {
+		return false
+	}
+	for name := range sections {
+		switch name {
+		case "seed", "variables", "functions", includeKey:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// includes returns the paths of the files to include, given as a string or
+// a list of strings.
+func (params parameters) includes() ([]string, error) {
+	if len(params.Include) == 0 {
+		return nil, nil
+	}
+	var include string
+	if json.Unmarshal(params.Include, &include) == nil {
+		return []string{include}, nil
+	}
+	var includes []string
+	if err := json.Unmarshal(params.Include, &includes); err != nil {
+		return nil, fmt.Errorf("%s must be a path or a list of paths", includeKey)
+	}
+	return includes, nil
+}
+
+// merge adds the template part of the file at path to params. owners
+// tells which file defined each name, as a name can't be defined by two
+// files.
+func (params *parameters) merge(other parameters, path string, owners map[string]string) error {
+	own := func(key, name string) error {
+		if owner, ok := owners[key]; ok && owner != path {
+			return fmt.Errorf("%s already defined in %s", name, owner)
+		}
+		owners[key] = path
+		return nil
+	}
+	if other.Seed != nil {
+		if err := own("seed", "seed"); err != nil {
+			return err
+		}
+		params.Seed = other.Seed
+	}
+	for _, name := range sortedKeys(other.Variables) {
+		if err := own("variable "+name, fmt.Sprintf("variable %q", name)); err != nil {
+			return err
+		}
+		if params.Variables == nil {
+			params.Variables = make(map[string]json.RawMessage)
+		}
+		params.Variables[name] = other.Variables[name]
+	}
+	if other.Functions == nil {
+		return nil
+	}
+	if params.Functions == nil {
+		params.Functions = &funcParams{}
+	}
+	fp, ofp := params.Functions, other.Functions
+	for _, name := range sortedKeys(ofp.Randoms) {
+		if err := own("function "+name, fmt.Sprintf("function %q", name)); err != nil {
+			return err
+		}
+		if fp.Randoms == nil {
+			fp.Randoms = make(map[string]random)
+		}
+		fp.Randoms[name] = ofp.Randoms[name]
+	}
+	for _, name := range sortedKeys(ofp.Arrays) {
+		if err := own("function "+name, fmt.Sprintf("function %q", name)); err != nil {
+			return err
+		}
+		if fp.Arrays == nil {
+			fp.Arrays = make(map[string]array)
+		}
+		fp.Arrays[name] = ofp.Arrays[name]
+	}
+	for _, name := range sortedKeys(ofp.Objects) {
+		if err := own("function "+name, fmt.Sprintf("function %q", name)); err != nil {
+			return err
+		}
+		if fp.Objects == nil {
+			fp.Objects = make(map[string]object)
+		}
+		fp.Objects[name] = ofp.Objects[name]
+	}
+	return nil
+}
+
+// merge adds the url part of the file at path to dbc. owners tells which
+// file defined each section, route and collection, as they can't be
+// defined by two files.
+func (dbc *dbContent) merge(other dbContent, path string, owners map[string]string) error {
+	own := func(key, route, name string) error {
+		if owner, ok := owners[key]; ok {
+			return &loadError{File: path, Section: urlSection, Route: route, Err: fmt.Errorf("%s already defined in %s", name, owner)}
+		}
+		owners[key] = path
+		return nil
+	}
+	if other.Server != nil {
+		if err := own("server", "", "server section"); err != nil {
+			return err
+		}
+		dbc.Server = other.Server
+	}
+	if other.CORS != nil {
+		if err := own("cors", "", "cors section"); err != nil {
+			return err
+		}
+		dbc.CORS = other.CORS
+	}
+	if other.Delay != nil {
+		if err := own("delay", "", "delay section"); err != nil {
+			return err
+		}
+		dbc.Delay = other.Delay
+	}
+	for _, key := range sortedKeys(other.URLs) {
+		if err := own("url "+key, key, "route"); err != nil {
+			return err
+		}
+		if dbc.URLs == nil {
+			dbc.URLs = make(map[string]route)
+		}
+		dbc.URLs[key] = other.URLs[key]
+	}
+	for _, name := range sortedKeys(other.Resources) {
+		if err := own("resource "+name, "", fmt.Sprintf("collection %q", name)); err != nil {
+			return err
+		}
+		if dbc.Resources == nil {
+			dbc.Resources = make(map[string][]map[string]interface{})
+		}
+		dbc.Resources[name] = other.Resources[name]
+	}
+	for _, name := range sortedKeys(other.Scenarios) {
+		if err := own("scenario "+name, "", fmt.Sprintf("scenario %q", name)); err != nil {
+			return err
+		}
+		if dbc.Scenarios == nil {
+			dbc.Scenarios = make(map[string]string)
+		}
+		dbc.Scenarios[name] = other.Scenarios[name]
+	}
+	return nil
+}
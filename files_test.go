@@ -0,0 +1,44 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestDBFiles(t *testing.T) {
+	tests := []struct {
+		db            string
+		expectedFiles []string
+		expectedError bool
+	}{
+		{db: "testdata/db_simple.json", expectedFiles: []string{"testdata/db_simple.json"}},
+		{db: "testdata/multi", expectedFiles: []string{"testdata/multi/orders.yaml", "testdata/multi/users.json", "testdata/multi/variables.json"}},
+		{db: "testdata/multi/*s.json", expectedFiles: []string{"testdata/multi/users.json", "testdata/multi/variables.json"}},
+		{db: "testdata/multi/*.xml", expectedError: true},
+		{db: "testdata/none", expectedError: true},
+	}
+	for i, test := range tests {
+		files, err := dbFiles(test.db)
+		if (err != nil) != test.expectedError {
+			t.Fatalf("Test %d: expected error %t, got %v", i, test.expectedError, err)
+		}
+		if strings.Join(files, " ") != strings.Join(test.expectedFiles, " ") {
+			t.Fatalf("Test %d: expected files %v, got %v", i, test.expectedFiles, files)
+		}
+	}
+}
+
+func TestMergeParameters(t *testing.T) {
+	owners := make(map[string]string)
+	var params parameters
+	first := parameters{Variables: map[string]json.RawMessage{"name": json.RawMessage(`"John"`)}}
+	if err := params.merge(first, "first.json", owners); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	second := parameters{Variables: map[string]json.RawMessage{"name": json.RawMessage(`"Jane"`)}}
+	err := params.merge(second, "second.json", owners)
+	if err == nil || !strings.Contains(err.Error(), "already defined in first.json") {
+		t.Fatalf("Expected the variable to be defined by the first file, got %v", err)
+	}
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// defaultSeparator is the line separating the url part of the db file from
+// its template part.
+const defaultSeparator = "---"
+
+// document is a db file split in its two parts. tmplLine is the line of the
+// file on which the template part starts, to report errors in it.
+type document struct {
+	urlPart  string
+	tmplPart string
+	tmplLine int
+}
+
+// splitDocument splits the body on the line made only of the separator,
+// spaces apart. The separator may appear anywhere else, in a string for
+// example. More than one separator line is an error.
+func splitDocument(body, separator string) (document, error) {
+	if separator == "" {
+		separator = defaultSeparator
+	}
+	lines := strings.Split(body, "\n")
+	sepLine := -1
+	for i, line := range lines {
+		if strings.TrimSpace(line) != separator {
+			continue
+		}
+		if sepLine >= 0 {
+			return document{}, &formatError{Line: i + 1, Msg: fmt.Sprintf("unexpected separator %q, the file already has one on line %d", separator, sepLine+1)}
+		}
+		sepLine = i
+	}
+	if sepLine < 0 {
+		return document{urlPart: body}, nil
+	}
+	return document{
+		urlPart:  strings.Join(lines[:sepLine], "\n"),
+		tmplPart: strings.Join(lines[sepLine+1:], "\n"),
+		tmplLine: sepLine + 2,
+	}, nil
+}
+
+// skipDocumentStart blanks the "---" starting a YAML document, so that it
+// isn't taken for the separator.
+func skipDocumentStart(body string) string {
+	lines := strings.Split(body, "\n")
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "%") {
+			continue
+		}
+		if line == "---" {
+			lines[i] = ""
+		}
+		break
+	}
+	return strings.Join(lines, "\n")
+}
+
+// fileFormat is the language the db file is written in. Every format is
+// turned into JSON before being decoded.
+type fileFormat int
+
+const (
+	jsonFormat fileFormat = iota
+	yamlFormat
+	tomlFormat
+)
+
+func (f fileFormat) String() string {
+	switch f {
+	case yamlFormat:
+		return "YAML"
+	case tomlFormat:
+		return "TOML"
+	}
+	return "JSON"
+}
+
+var (
+	tomlTableRegexp = regexp.MustCompile(`^\[\[?\s*[A-Za-z0-9_"'./ -]+\s*\]\]?\s*(#.*)?$`)
+	tomlKeyRegexp   = regexp.MustCompile(`^[A-Za-z0-9_"'.-]+\s*=`)
+)
+
+var extensions = map[string]fileFormat{
+	".json":  jsonFormat,
+	".json5": jsonFormat,
+	".jsonc": jsonFormat,
+	".yaml":  yamlFormat,
+	".yml":   yamlFormat,
+	".toml":  tomlFormat,
+}
+
+// knownExtension tells if the file has the extension of a db format.
+func knownExtension(path string) bool {
+	_, ok := extensions[strings.ToLower(filepath.Ext(path))]
+	return ok
+}
+
+// detectFormat returns the format of the file from its extension, or from
+// its first line if the extension isn't known. JSON5 and JSONC are read as
+// JSON, which accepts their comments, trailing commas and other additions.
+func detectFormat(path, body string) fileFormat {
+	if format, ok := extensions[strings.ToLower(filepath.Ext(path))]; ok {
+		return format
+	}
+	for _, line := range strings.Split(body, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "//"):
+			continue
+		case strings.HasPrefix(line, "{"):
+			return jsonFormat
+		case tomlTableRegexp.MatchString(line) || tomlKeyRegexp.MatchString(line):
+			return tomlFormat
+		}
+		return yamlFormat
+	}
+	return jsonFormat
+}
+
+// toJSON turns a part of the file into JSON. For JSON, the lines and the
+// offsets are kept, so that the errors found when decoding point to the
+// file.
+func (f fileFormat) toJSON(text string) (string, error) {
+	switch f {
+	case yamlFormat:
+		return yamlToJSON(text)
+	case tomlFormat:
+		return tomlToJSON(text)
+	}
+	return json5ToJSON(text)
+}
+
+// keepsLines tells if the JSON of the format has the lines of the file.
+func (f fileFormat) keepsLines() bool {
+	return f == jsonFormat
+}
+
+// formatError is a syntax error in a YAML, TOML or JSON5 text.
+type formatError struct {
+	Line   int
+	Column int
+	Msg    string
+}
+
+func (e *formatError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+}
+
+// orderedMap is an object read from a YAML or TOML text, keeping the order
+// of its keys when written as JSON.
+type orderedMap struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func newOrderedMap() *orderedMap {
+	return &orderedMap{values: make(map[string]interface{})}
+}
+
+func (m *orderedMap) get(key string) (interface{}, bool) {
+	v, ok := m.values[key]
+	return v, ok
+}
+
+// set sets the value of the key, adding it at the end if it is new.
+func (m *orderedMap) set(key string, value interface{}) {
+	if _, ok := m.values[key]; !ok {
+		m.keys = append(m.keys, key)
+	}
+	m.values[key] = value
+}
+
+// writeJSON writes a value made of ordered maps, slices and JSON scalars.
+func writeJSON(buf *bytes.Buffer, value interface{}) {
+	switch v := value.(type) {
+	case *orderedMap:
+		buf.WriteByte('{')
+		for i, key := range v.keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			writeJSON(buf, key)
+			buf.WriteByte(':')
+			writeJSON(buf, v.values[key])
+		}
+		buf.WriteByte('}')
+	case []interface{}:
+		buf.WriteByte('[')
+		for i, elt := range v {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			writeJSON(buf, elt)
+		}
+		buf.WriteByte(']')
+	case string:
+		enc := json.NewEncoder(buf)
+		enc.SetEscapeHTML(false)
+		enc.Encode(v)
+		// Encode ends the value with a new line
+		buf.Truncate(buf.Len() - 1)
+	case json.RawMessage:
+		buf.Write(v)
+	case nil:
+		buf.WriteString("null")
+	default:
+		b, _ := json.Marshal(v)
+		buf.Write(b)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestSplitDocument(t *testing.T) {
+	tests := []struct {
+		body        string
+		separator   string
+		expectedURL string
+		expectedTpl string
+		expectedLn  int
+		expectedErr bool
+	}{
+		{
+			body:        `{"urls": {}}`,
+			expectedURL: `{"urls": {}}`,
+		},
+		{
+			body:        "{\"urls\": {\"/a\": {\"json\": \"---\"}}}\n---\n{}",
+			expectedURL: "{\"urls\": {\"/a\": {\"json\": \"---\"}}}",
+			expectedTpl: "{}",
+			expectedLn:  3,
+		},
+		{
+			body:        "{\n}\n  ---  \n{\n}",
+			expectedURL: "{\n}",
+			expectedTpl: "{\n}",
+			expectedLn:  4,
+		},
+		{
+			body:        "{}\n###\n{}",
+			separator:   "###",
+			expectedURL: "{}",
+			expectedTpl: "{}",
+			expectedLn:  3,
+		},
+		{
+			body:        "{}\n---\n{}",
+			separator:   "###",
+			expectedURL: "{}\n---\n{}",
+		},
+		{
+			body:        "{}\n---\n{}\n---\n{}",
+			expectedErr: true,
+		},
+	}
+	for i, test := range tests {
+		doc, err := splitDocument(test.body, test.separator)
+		if (err != nil) != test.expectedErr {
+			t.Fatalf("Test %d: expected error %t, got %v", i, test.expectedErr, err)
+		}
+		if doc.urlPart != test.expectedURL {
+			t.Fatalf("Test %d: expected url part %q, got %q", i, test.expectedURL, doc.urlPart)
+		}
+		if doc.tmplPart != test.expectedTpl {
+			t.Fatalf("Test %d: expected template part %q, got %q", i, test.expectedTpl, doc.tmplPart)
+		}
+		if doc.tmplLine != test.expectedLn {
+			t.Fatalf("Test %d: expected the template part on line %d, got %d", i, test.expectedLn, doc.tmplLine)
+		}
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		path           string
+		body           string
+		expectedFormat fileFormat
+	}{
+		{path: "db.json", body: "urls: {}", expectedFormat: jsonFormat},
+		{path: "db.JSON5", expectedFormat: jsonFormat},
+		{path: "db.jsonc", expectedFormat: jsonFormat},
+		{path: "db.yml", body: "{}", expectedFormat: yamlFormat},
+		{path: "db.yaml", expectedFormat: yamlFormat},
+		{path: "db.toml", expectedFormat: tomlFormat},
+		{path: "db", body: "// comment\n{\"urls\": {}}", expectedFormat: jsonFormat},
+		{path: "db.mock", body: "# comment\n\nurls:\n  /test: {}", expectedFormat: yamlFormat},
+		{path: "db.mock", body: "---\nurls: {}", expectedFormat: yamlFormat},
+		{path: "db.mock", body: "# comment\n[urls.\"/test\"]\njson = 1", expectedFormat: tomlFormat},
+		{path: "db.mock", body: "title = \"mock\"", expectedFormat: tomlFormat},
+		{path: "db.mock", expectedFormat: jsonFormat},
+	}
+	for i, test := range tests {
+		if format := detectFormat(test.path, test.body); format != test.expectedFormat {
+			t.Fatalf("Test %d: expected the format %v, got %v", i, test.expectedFormat, format)
+		}
+	}
+}
@@ -4,6 +4,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"math/rand"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
 
 	"github.com/evermax/iseva/util"
 )
@@ -11,54 +15,143 @@ import (
 type funcParams struct {
 	Randoms map[string]random `json:"rand"`
 	Arrays  map[string]array  `json:"array"`
+	Objects map[string]object `json:"object"`
 }
 
 type random struct {
-	Type string `json:"type"`
-	Size int    `json:"size"`
-	Min  int    `json:"min"`
-	Max  int    `json:"max"`
+	Type    string            `json:"type"`
+	Size    int               `json:"size"`
+	Min     int               `json:"min"`
+	Max     int               `json:"max"`
+	From    string            `json:"from"`
+	To      string            `json:"to"`
+	Values  []json.RawMessage `json:"values"`
+	Weights []float64         `json:"weights"`
+	Stable  bool              `json:"stable"`
 }
 
 type array struct {
-	Type      string `json:"type"`
-	ArraySize int    `json:"arraysize"`
-	Size      int    `json:"size"`
-	Min       int    `json:"min"`
-	Max       int    `json:"max"`
+	Type      string            `json:"type"`
+	ArraySize int               `json:"arraysize"`
+	Size      int               `json:"size"`
+	Min       int               `json:"min"`
+	Max       int               `json:"max"`
+	From      string            `json:"from"`
+	To        string            `json:"to"`
+	Values    []json.RawMessage `json:"values"`
+	Weights   []float64         `json:"weights"`
+	Stable    bool              `json:"stable"`
 }
 
-func (fp funcParams) parse() (fcts map[string]interface{}) {
+// element returns the parameters of the elements of the array.
+func (arrParam array) element() random {
+	return random{
+		Type:    arrParam.Type,
+		Size:    arrParam.Size,
+		Min:     arrParam.Min,
+		Max:     arrParam.Max,
+		From:    arrParam.From,
+		To:      arrParam.To,
+		Values:  arrParam.Values,
+		Weights: arrParam.Weights,
+	}
+}
+
+// defaultRand is used by the functions when the file gives no seed.
+var defaultRand = rand.New(util.NewSource(time.Now().UnixNano()))
+
+func newRand(seed int64) *rand.Rand {
+	return rand.New(util.NewSource(seed))
+}
+
+func (fp funcParams) parse() map[string]interface{} {
+	return fp.parseRand(defaultRand)
+}
+
+// parseRand returns the functions taking their random values from rnd. The
+// functions are built in the order of their names, so that a seeded rnd
+// always gives the same values. Each call gives a new value, unless the
+// function is stable.
+func (fp funcParams) parseRand(rnd *rand.Rand) (fcts map[string]interface{}) {
 	fcts = make(map[string]interface{})
-	for name, randomParam := range fp.Randoms {
+	for _, name := range sortedKeys(fp.Randoms) {
+		randomParam := fp.Randoms[name]
+		if isChoice(randomParam.Type) {
+			if len(randomParam.Values) > 0 {
+				fcts[name] = randomParam.choiceFunc(rnd)
+			}
+			continue
+		}
+		if faker, ok := fakers[randomParam.Type]; ok {
+			fcts[name] = func() string {
+				return fmt.Sprint(faker(rnd, randomParam))
+			}
+			continue
+		}
 		if randomParam.Max > randomParam.Min {
 			switch randomParam.Type {
 			case "string":
 				if randomParam.Min >= 0 {
-					length := randomParam.Min + rand.Intn(randomParam.Max-randomParam.Min)
 					fcts[name] = func() string {
-						return util.RandString(length)
+						length := randomParam.Min + rnd.Intn(randomParam.Max-randomParam.Min)
+						return util.RandStringFrom(rnd, length)
 					}
 				}
 			case "int":
-				val := randomParam.Min + rand.Intn(randomParam.Max-randomParam.Min)
 				fcts[name] = func() string {
-					return fmt.Sprintf("%d", val)
+					return fmt.Sprintf("%d", randomParam.Min+rnd.Intn(randomParam.Max-randomParam.Min))
 				}
 			case "float":
 				fcts[name] = func() string {
-					return fmt.Sprintf("%f", float64(randomParam.Min)+rand.Float64()*float64(randomParam.Max-randomParam.Min))
+					return fmt.Sprintf("%f", float64(randomParam.Min)+rnd.Float64()*float64(randomParam.Max-randomParam.Min))
 				}
 			}
 		} else if randomParam.Size > 0 {
 			if randomParam.Type == "string" {
 				fcts[name] = func() string {
-					return util.RandString(randomParam.Size)
+					return util.RandStringFrom(rnd, randomParam.Size)
 				}
 			}
 		}
 	}
-	for name, arrParam := range fp.Arrays {
+	for _, name := range sortedKeys(fp.Arrays) {
+		arrParam := fp.Arrays[name]
+		if isChoice(arrParam.Type) && arrParam.ArraySize > 0 && len(arrParam.Values) > 0 {
+			size := arrParam.ArraySize
+			elemParam := arrParam.element()
+			fcts[name] = func() (string, error) {
+				var array = make([]interface{}, size)
+				for i := 0; i < size; i++ {
+					if elemParam.Type == "sample" {
+						array[i] = elemParam.sample(rnd)
+					} else {
+						array[i] = elemParam.pick(rnd)
+					}
+				}
+				arr, err := json.Marshal(array)
+				if err != nil {
+					return "", err
+				}
+				return string(arr), nil
+			}
+			continue
+		}
+		if faker, ok := fakers[arrParam.Type]; ok && arrParam.ArraySize > 0 {
+			size := arrParam.ArraySize
+			elemParam := arrParam.element()
+			fcts[name] = func() (string, error) {
+				var array = make([]interface{}, size)
+				for i := 0; i < size; i++ {
+					array[i] = faker(rnd, elemParam)
+				}
+				arr, err := json.Marshal(array)
+				if err != nil {
+					return "", err
+				}
+				return string(arr), nil
+			}
+			continue
+		}
 		if arrParam.ArraySize > 0 {
 			if arrParam.Max > arrParam.Min {
 				switch arrParam.Type {
@@ -69,8 +162,8 @@ func (fp funcParams) parse() (fcts map[string]interface{}) {
 					fcts[name] = func() (string, error) {
 						var array = make([]string, size)
 						for i := 0; i < size; i++ {
-							length := arrParam.Min + rand.Intn(max-min)
-							array[i] = util.RandString(length)
+							length := arrParam.Min + rnd.Intn(max-min)
+							array[i] = util.RandStringFrom(rnd, length)
 						}
 						arr, err := json.Marshal(array)
 						if err != nil {
@@ -85,7 +178,7 @@ func (fp funcParams) parse() (fcts map[string]interface{}) {
 					fcts[name] = func() (string, error) {
 						var array = make([]int, size)
 						for i := 0; i < size; i++ {
-							array[i] = min + rand.Intn(max-min)
+							array[i] = min + rnd.Intn(max-min)
 						}
 						arr, err := json.Marshal(array)
 						if err != nil {
@@ -100,7 +193,7 @@ func (fp funcParams) parse() (fcts map[string]interface{}) {
 					fcts[name] = func() (string, error) {
 						var array = make([]float64, size)
 						for i := 0; i < size; i++ {
-							array[i] = float64(min) + rand.Float64()*float64(max-min)
+							array[i] = float64(min) + rnd.Float64()*float64(max-min)
 						}
 						arr, err := json.Marshal(array)
 						if err != nil {
@@ -115,7 +208,7 @@ func (fp funcParams) parse() (fcts map[string]interface{}) {
 					fcts[name] = func() (string, error) {
 						var array = make([]string, size)
 						for i := 0; i < size; i++ {
-							array[i] = util.RandString(size)
+							array[i] = util.RandStringFrom(rnd, size)
 						}
 						arr, err := json.Marshal(array)
 						if err != nil {
@@ -127,5 +220,55 @@ func (fp funcParams) parse() (fcts map[string]interface{}) {
 			}
 		}
 	}
+	for _, name := range sortedKeys(fp.Objects) {
+		obj := fp.Objects[name]
+		schema, err := obj.decodeSchema()
+		if err != nil || fp.objectCycle(name, nil) != nil {
+			continue
+		}
+		fcts[name] = fp.objectFunc(schema, obj.ArraySize, fcts)
+	}
+	for name, f := range fcts {
+		if fp.isStable(name) {
+			fcts[name] = stable(f)
+		}
+	}
 	return
 }
+
+// isStable tells if the function name gives the same value on every call.
+func (fp funcParams) isStable(name string) bool {
+	return fp.Randoms[name].Stable || fp.Arrays[name].Stable || fp.Objects[name].Stable
+}
+
+// stable returns a function computing the value of f on its first call
+// and giving it back on the next ones.
+func stable(f interface{}) interface{} {
+	var once sync.Once
+	var out string
+	var err error
+	switch f := f.(type) {
+	case func() string:
+		return func() string {
+			once.Do(func() { out = f() })
+			return out
+		}
+	case func() (string, error):
+		return func() (string, error) {
+			once.Do(func() { out, err = f() })
+			return out, err
+		}
+	}
+	return f
+}
+
+// sortedKeys returns the sorted keys of a map with string keys.
+func sortedKeys(m interface{}) []string {
+	keys := reflect.ValueOf(m).MapKeys()
+	names := make([]string, len(keys))
+	for i, key := range keys {
+		names[i] = key.String()
+	}
+	sort.Strings(names)
+	return names
+}
@@ -439,3 +439,142 @@ func TestArrayStringSize(t *testing.T) {
 		}
 	}
 }
+
+func TestParseRandSeed(t *testing.T) {
+	params := funcParams{
+		Randoms: map[string]random{
+			"int":    random{Type: "int", Min: 0, Max: 1000000},
+			"string": random{Type: "string", Min: 5, Max: 50},
+		},
+		Arrays: map[string]array{
+			"floatarray": array{Type: "float", ArraySize: 5, Min: 0, Max: 1000},
+		},
+	}
+	call := func(functions map[string]interface{}) string {
+		result, err := functions["floatarray"].(func() (string, error))()
+		if err != nil {
+			t.Fatalf("An error occured when execution the function: %v", err)
+		}
+		return functions["int"].(func() string)() + functions["string"].(func() string)() + result
+	}
+	first := call(params.parseRand(newRand(42)))
+	second := call(params.parseRand(newRand(42)))
+	if first != second {
+		t.Fatalf("Expected the same values with the same seed, got %s and %s", first, second)
+	}
+	if other := call(params.parseRand(newRand(43))); other == first {
+		t.Fatalf("Expected different values with another seed, got %s", other)
+	}
+}
+
+func TestObject(t *testing.T) {
+	params := funcParams{
+		Randoms: map[string]random{
+			"id":   random{Type: "int", Min: 1, Max: 100},
+			"name": random{Type: "name"},
+			"city": random{Type: "city"},
+		},
+		Arrays: map[string]array{
+			"scores": array{Type: "int", ArraySize: 3, Min: 0, Max: 10},
+		},
+		Objects: map[string]object{
+			"address": object{
+				Schema: json.RawMessage(`{"city": "$city", "country": "France"}`),
+			},
+			"user": object{
+				Schema: json.RawMessage(`{"id": "$id", "name": "$name", "address": "$address", "scores": "$scores", "price": "$$10", "active": true, "tags": ["admin", {"level": 3}]}`),
+			},
+			"users": object{
+				Schema:    json.RawMessage(`"$user"`),
+				ArraySize: 4,
+			},
+			"loop": object{
+				Schema: json.RawMessage(`{"next": "$loop"}`),
+			},
+		},
+	}
+	functions := params.parse()
+	if _, ok := functions["loop"]; ok {
+		t.Fatalf("Expected the object referencing itself to be ignored")
+	}
+	f, ok := functions["users"].(func() (string, error))
+	if !ok {
+		t.Fatalf("Error when parsing the function %s, the actual type is %T", "users", functions["users"])
+	}
+	result, err := f()
+	if err != nil {
+		t.Fatalf("An error occured when execution the function: %v", err)
+	}
+	var users []struct {
+		ID      int    `json:"id"`
+		Name    string `json:"name"`
+		Price   string `json:"price"`
+		Active  bool   `json:"active"`
+		Scores  []int  `json:"scores"`
+		Tags    []interface{}
+		Address struct {
+			City    string `json:"city"`
+			Country string `json:"country"`
+		} `json:"address"`
+	}
+	if err := json.Unmarshal([]byte(result), &users); err != nil {
+		t.Fatalf("Error while unmarshalling the result: %s, the error was: %v", result, err)
+	}
+	if len(users) != 4 {
+		t.Fatalf("Expected 4 users, got %d in %s", len(users), result)
+	}
+	for _, user := range users {
+		if user.ID < 1 || user.ID > 100 || user.Name == "" || user.Price != "$10" || !user.Active || len(user.Scores) != 3 || len(user.Tags) != 2 {
+			t.Fatalf("The user doesn't follow the schema: %s", result)
+		}
+		if user.Address.City == "" || user.Address.Country != "France" {
+			t.Fatalf("The address doesn't follow the schema: %s", result)
+		}
+	}
+}
+
+func TestStable(t *testing.T) {
+	params := funcParams{
+		Randoms: map[string]random{
+			"int":       random{Type: "int", Min: 0, Max: 1000000},
+			"stableint": random{Type: "int", Min: 0, Max: 1000000, Stable: true},
+			"string":    random{Type: "string", Min: 1, Max: 50},
+		},
+		Objects: map[string]object{
+			"user": object{
+				Schema: json.RawMessage(`{"id": "$stableint"}`),
+				Stable: true,
+			},
+		},
+	}
+	functions := params.parse()
+	values := make(map[string]map[string]bool)
+	for n := 0; n < 20; n++ {
+		for _, name := range []string{"int", "stableint", "string"} {
+			if values[name] == nil {
+				values[name] = make(map[string]bool)
+			}
+			values[name][functions[name].(func() string)()] = true
+		}
+		user, err := functions["user"].(func() (string, error))()
+		if err != nil {
+			t.Fatalf("An error occured when execution the function: %v", err)
+		}
+		if values["user"] == nil {
+			values["user"] = make(map[string]bool)
+		}
+		values["user"][user] = true
+	}
+	for name, expected := range map[string]bool{"int": false, "stableint": true, "string": false, "user": true} {
+		if stable := len(values[name]) == 1; stable != expected {
+			t.Fatalf("Expected the function %s to be stable: %v, got the values %v", name, expected, values[name])
+		}
+	}
+	for user := range values["user"] {
+		for id := range values["stableint"] {
+			if user != `{"id":`+id+`}` {
+				t.Fatalf("Expected the user to reuse the stable id %s, got %s", id, user)
+			}
+		}
+	}
+}
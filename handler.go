@@ -4,16 +4,41 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
+	"math/rand"
 	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 	"text/template"
+	"time"
 )
 
 type JSONHandler struct {
-	DB       string
-	IsStatic bool
-	dbc      dbContent
+	DB         string
+	IsStatic   bool
+	PerRequest bool
+	Watch      bool
+	State      string
+	Separator  string
+	BasePath   string
+	Seed       *int64
+	mu         sync.RWMutex
+	content    *content
+	resources  *resourceStore
+	scenarios  *scenarioStore
+}
+
+// content is what is loaded from the db files. It is never modified once
+// loaded: reloading the files replaces it.
+type content struct {
+	files  []*sourceFile
+	dbc    dbContent
+	params parameters
+	vars   map[string]string
+	routes *router
 }
 
 func NewJSONHandler(db string, isStatic bool) (*JSONHandler, error) {
@@ -21,87 +46,458 @@ func NewJSONHandler(db string, isStatic bool) (*JSONHandler, error) {
 	return handler, handler.getDBData()
 }
 
+// Flush writes the changes of the resources waiting to be saved in the
+// state file.
+func (handler *JSONHandler) Flush() error {
+	_, resources, _ := handler.current()
+	return resources.flush()
+}
+
 func (handler *JSONHandler) getDBData() error {
-	body, err := ioutil.ReadFile(handler.DB)
+	c, err := handler.load()
 	if err != nil {
 		return err
 	}
-	if strings.Contains(string(body), "---") {
-		var params parameters
-		bodyArr := strings.Split(string(body), "---")
-		if err := json.Unmarshal([]byte(bodyArr[1]), &params); err != nil {
-			return err
+	return handler.swap(c)
+}
+
+// swap replaces the content served, and seeds the resources with it.
+func (handler *JSONHandler) swap(c *content) error {
+	handler.mu.Lock()
+	defer handler.mu.Unlock()
+	if handler.resources == nil {
+		resources := newResourceStore()
+		if handler.State != "" {
+			if err := resources.persist(handler.State); err != nil {
+				return err
+			}
+		}
+		handler.resources = resources
+		handler.scenarios = newScenarioStore()
+	}
+	handler.resources.seed(c.dbc.Resources)
+	handler.scenarios.seed(c.dbc.Scenarios)
+	handler.content = c
+	return nil
+}
+
+// serverConfig returns the server section of the file being served.
+func (handler *JSONHandler) serverConfig() serverConfig {
+	c, _, _ := handler.current()
+	if c.dbc.Server == nil {
+		return serverConfig{}
+	}
+	return *c.dbc.Server
+}
+
+// current returns the content, the resources and the scenarios being
+// served. They can be used without holding the lock as the content is
+// never modified and the stores have their own lock.
+func (handler *JSONHandler) current() (*content, *resourceStore, *scenarioStore) {
+	handler.mu.RLock()
+	defer handler.mu.RUnlock()
+	if handler.content == nil {
+		return &content{}, handler.resources, handler.scenarios
+	}
+	return handler.content, handler.resources, handler.scenarios
+}
+
+// watch reloads the db files when one of them changes, is added or is
+// removed, checking every interval until done is closed. If the new files
+// are broken, the error is printed and the last good content is kept.
+func (handler *JSONHandler) watch(interval time.Duration, done <-chan struct{}) {
+	c, _, _ := handler.current()
+	stamp := c.stamp()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
 		}
-		elts := params.parse()
-		tmpl, err := template.New("JSONtemplate").Funcs(elts.Func).Parse(bodyArr[0])
+		c, _, _ := handler.current()
+		current, err := handler.stamp(c)
 		if err != nil {
-			return err
+			fmt.Println(err)
+			continue
+		}
+		if current == stamp {
+			continue
+		}
+		stamp = current
+		if err := handler.getDBData(); err != nil {
+			fmt.Printf("Keeping the previous version of %s: %v\n", handler.DB, err)
+			continue
+		}
+		fmt.Printf("Reloaded %s\n", handler.DB)
+	}
+}
+
+func (handler *JSONHandler) load() (*content, error) {
+	paths, err := handler.dbFiles()
+	if err != nil {
+		return nil, err
+	}
+	l := newLoader(handler.Separator)
+	for _, path := range paths {
+		if err := l.read(path); err != nil {
+			return nil, err
+		}
+	}
+	params := l.params
+	if params.Functions != nil {
+		if err := params.Functions.validate(); err != nil {
+			return nil, l.functionsError(err)
 		}
-		var buf bytes.Buffer
-		err = tmpl.Execute(&buf, elts.Var)
+	}
+	rnd := defaultRand
+	if handler.Seed != nil {
+		rnd = newRand(*handler.Seed)
+	} else if params.Seed != nil {
+		rnd = newRand(*params.Seed)
+	}
+	elts := params.parse(rnd)
+	for _, f := range l.files {
+		if strings.TrimSpace(f.source) == "" {
+			continue
+		}
+		f.tmpl, err = template.New("JSONtemplate").Option("missingkey=zero").
+			Funcs(template.FuncMap{"json": toJSON}).Funcs(elts.Func).Parse(f.source)
 		if err != nil {
-			return err
+			return nil, templateError(f.path, f.source, err)
 		}
-		if err := json.Unmarshal(buf.Bytes(), &handler.dbc); err != nil {
-			return err
+	}
+	c := &content{
+		files:  l.files,
+		params: params,
+		vars:   elts.Var,
+	}
+	c.dbc, err = c.render(emptyRequestData(), nil)
+	if err != nil {
+		return nil, err
+	}
+	c.routes = newRouter(c.dbc.URLs)
+	return c, nil
+}
+
+// render executes the url parts of the files, and merges them. The
+// variables are available at the root of the templates next to the
+// request data, which is empty when loading the files. If rnd isn't nil,
+// the functions take their random values from it instead of the source of
+// the files.
+func (c *content) render(req requestData, rnd *rand.Rand) (dbContent, error) {
+	data := make(map[string]interface{}, len(c.vars)+7)
+	for k, v := range c.vars {
+		data[k] = v
+	}
+	req.fill(data)
+	var funcs template.FuncMap
+	if rnd != nil {
+		funcs = c.params.parse(rnd).Func
+	}
+	var dbc dbContent
+	owners := make(map[string]string)
+	for _, f := range c.files {
+		if f.tmpl == nil {
+			continue
 		}
-	} else {
-		if err := json.Unmarshal(body, &handler.dbc); err != nil {
-			return err
+		fileDBC, err := f.render(data, funcs)
+		if err != nil {
+			return dbContent{}, err
+		}
+		if err := dbc.merge(fileDBC, f.path, owners); err != nil {
+			return dbContent{}, err
 		}
 	}
-	return nil
+	return dbc, nil
+}
+
+// render executes the url part of the file, with funcs instead of the
+// functions it was parsed with if they are given.
+func (f *sourceFile) render(data map[string]interface{}, funcs template.FuncMap) (dbContent, error) {
+	tmpl := f.tmpl
+	if funcs != nil {
+		var err error
+		if tmpl, err = f.tmpl.Clone(); err != nil {
+			return dbContent{}, err
+		}
+		tmpl.Funcs(funcs)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return dbContent{}, templateError(f.path, f.source, err)
+	}
+	urlJSON, err := f.format.toJSON(buf.String())
+	if err != nil {
+		return dbContent{}, formatLoadError(f.path, urlSection, buf.String(), 1, err)
+	}
+	var dbc dbContent
+	if err := json.Unmarshal([]byte(urlJSON), &dbc); err != nil {
+		return dbContent{}, urlsError(f.path, urlJSON, err).located(f.format)
+	}
+	dbc.resolveFiles(filepath.Dir(f.path))
+	return dbc, nil
 }
 
 func (handler *JSONHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", jsonContentType)
+
+	c, resources, scenarios := handler.current()
+	if !handler.IsStatic && !handler.Watch {
+		// the content loaded is used for this request even if another
+		// request swaps its own in the meantime
+		loaded, err := handler.load()
+		if err == nil {
+			err = handler.swap(loaded)
+		}
+		if err != nil {
+			fmt.Println(err)
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		_, resources, scenarios = handler.current()
+		c = loaded
+	}
+	basePath := handler.BasePath
+	if basePath == "" && c.dbc.Server != nil {
+		basePath = c.dbc.Server.BasePath
+	}
+	var cors corsConfig
+	if c.dbc.CORS != nil {
+		cors = *c.dbc.CORS
+	}
+	path, ok := stripBasePath(r.URL.Path, basePath)
+	if !ok {
+		cors.apply(w, r, r.Method == "OPTIONS")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if path != r.URL.Path {
+		stripped := *r
+		stripped.URL = new(url.URL)
+		*stripped.URL = *r.URL
+		stripped.URL.Path = path
+		stripped.URL.RawPath = ""
+		r = &stripped
+	}
+	key, params, found := c.routes.match(r.URL.Path)
+	if found {
+		cors = cors.merge(c.dbc.URLs[key].CORS)
+	}
 	// support for cross domain options calls
-	w.Header().Add("Access-Control-Allow-Header", "Content-Type, X-Requested-With")
-	w.Header().Add("Content-Type", "application/json; charset=utf-8")
 	if r.Method == "OPTIONS" {
-		if origin := r.Header.Get("origin"); origin != "" {
-			w.Header().Add("Access-Control-Allow-Origin", origin)
-		}
+		cors.apply(w, r, true)
 		w.WriteHeader(http.StatusNoContent)
 		return
 	}
-
-	if !handler.IsStatic {
-		err := handler.getDBData()
+	cors.apply(w, r, false)
+	// the scenarios endpoint is under the base path, next to the urls it
+	// changes, and answers the browsers like them
+	if scenarios != nil && (r.URL.Path == scenariosPath || strings.HasPrefix(r.URL.Path, scenariosPath+"/")) {
+		scenarios.serve(w, r, c.dbc)
+		return
+	}
+	delay := c.dbc.Delay
+	if found && c.dbc.URLs[key].Delay != nil {
+		delay = c.dbc.URLs[key].Delay
+	}
+	if asked, err := requestDelay(r); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	} else if asked != nil {
+		delay = asked
+	}
+	wait(r, delay.duration())
+	rnd, err := requestRand(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if found {
+		route := c.dbc.URLs[key]
+		req, err := newRequestData(r, params)
 		if err != nil {
 			fmt.Println(err)
-			w.WriteHeader(http.StatusInternalServerError)
+			w.WriteHeader(http.StatusBadRequest)
 			return
 		}
-	}
-	if raw, ok := handler.dbc.URLs[r.URL.Path]; ok {
-		if origin := r.Header.Get("origin"); origin != "" {
-			w.Header().Add("Access-Control-Allow-Origin", origin)
+		if params != nil || handler.PerRequest || rnd != nil {
+			dbc, err := c.render(req, rnd)
+			if err != nil {
+				fmt.Println(err)
+				writeError(w, http.StatusInternalServerError, err)
+				return
+			}
+			route = dbc.URLs[key]
+		}
+		raw, ok := route.response(r.Method)
+		if !ok {
+			w.Header().Set("Allow", strings.Join(route.allowed(), ", "))
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
 		}
-		w.WriteHeader(http.StatusOK)
-		w.Write(raw.JSON)
-	} else {
+		raw = scenarios.answer(raw, req, r.Method+" "+r.URL.Path)
+		body, contentType, err := raw.body()
+		if err != nil {
+			fmt.Println(err)
+			status := http.StatusInternalServerError
+			if os.IsNotExist(err) {
+				status = http.StatusNotFound
+			}
+			writeError(w, status, err)
+			return
+		}
+		w.Header().Set("Content-Type", contentType)
+		for name, value := range raw.Headers {
+			w.Header().Set(name, value)
+		}
+		route.Chaos.write(w, r, raw.status(), body)
+	} else if !resources.serve(w, r, basePath) {
 		w.WriteHeader(http.StatusNotFound)
 		return
 	}
 }
 
 type dbContent struct {
-	URLs map[string]raw `json:"urls"`
+	Server    *serverConfig                       `json:"server"`
+	CORS      *corsConfig                         `json:"cors"`
+	Delay     *delayConfig                        `json:"delay"`
+	URLs      map[string]route                    `json:"urls"`
+	Resources map[string][]map[string]interface{} `json:"resources"`
+	Scenarios map[string]string                   `json:"scenarios"`
 }
 
+// raw is the answer of a route: a JSON value, a text body like HTML, XML
+// or CSV, or the content of a file relative to the db file. The content
+// type is found from the body when it isn't given. The variants answer
+// the requests matching their conditions instead, the sequence answers the
+// successive calls, and the states answer depending on the state of the
+// scenario, which next moves to another state.
 type raw struct {
-	JSON json.RawMessage `json:"json"`
+	JSON        json.RawMessage   `json:"json"`
+	Body        string            `json:"body"`
+	File        string            `json:"file"`
+	ContentType string            `json:"contenttype"`
+	Status      int               `json:"status"`
+	Headers     map[string]string `json:"headers"`
+	Variants    []variant         `json:"variants"`
+	Sequence    []raw             `json:"sequence"`
+	Cycle       bool              `json:"cycle"`
+	Scenario    string            `json:"scenario"`
+	States      map[string]raw    `json:"states"`
+	Next        string            `json:"next"`
+}
+
+func (r raw) status() int {
+	if r.Status == 0 {
+		return http.StatusOK
+	}
+	return r.Status
+}
+
+// route is an entry of the urls section. It is either a flat raw answering
+// every method, or an object keyed by HTTP method ("GET", "POST", ...).
+// When both are given, the flat form answers the methods not listed.
+type route struct {
+	raw
+	routeOptions
+	Methods map[string]raw
+}
+
+// routeOptions are the fields of a route that apply to all its methods.
+type routeOptions struct {
+	CORS  *corsConfig  `json:"cors"`
+	Delay *delayConfig `json:"delay"`
+	Chaos *chaosConfig `json:"chaos"`
+}
+
+func (rt *route) UnmarshalJSON(data []byte) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	for key, value := range fields {
+		if !isMethod(key) {
+			continue
+		}
+		var methodRaw raw
+		if err := json.Unmarshal(value, &methodRaw); err != nil {
+			return fmt.Errorf("method %s: %v", key, err)
+		}
+		if err := methodRaw.check(); err != nil {
+			return fmt.Errorf("method %s: %v", key, err)
+		}
+		if rt.Methods == nil {
+			rt.Methods = make(map[string]raw)
+		}
+		rt.Methods[key] = methodRaw
+	}
+	if err := json.Unmarshal(data, &rt.routeOptions); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, &rt.raw); err != nil {
+		return err
+	}
+	return rt.raw.check()
+}
+
+// response returns the raw to serve for the given method, false if the
+// route doesn't answer it. HEAD is answered like GET when it isn't
+// declared, the server leaving out the body.
+func (rt route) response(method string) (raw, bool) {
+	if methodRaw, ok := rt.Methods[method]; ok {
+		return methodRaw, true
+	}
+	if getRaw, ok := rt.Methods["GET"]; ok && method == "HEAD" {
+		return getRaw, true
+	}
+	if len(rt.Methods) == 0 || rt.hasBody() {
+		return rt.raw, true
+	}
+	return raw{}, false
+}
+
+// allowed returns the sorted list of methods declared on the route, with
+// HEAD when GET is.
+func (rt route) allowed() []string {
+	methods := make([]string, 0, len(rt.Methods)+1)
+	for method := range rt.Methods {
+		methods = append(methods, method)
+	}
+	_, head := rt.Methods["HEAD"]
+	if _, get := rt.Methods["GET"]; get && !head {
+		methods = append(methods, "HEAD")
+	}
+	sort.Strings(methods)
+	return methods
+}
+
+// isMethod tells if a key of a route is an HTTP method, which are written
+// in upper case to keep them apart from the other fields.
+func isMethod(key string) bool {
+	if key == "" {
+		return false
+	}
+	for _, c := range key {
+		if c < 'A' || c > 'Z' {
+			return false
+		}
+	}
+	return true
 }
 
 type parameters struct {
+	Seed      *int64                     `json:"seed"`
 	Variables map[string]json.RawMessage `json:"variables"`
 	Functions *funcParams                `json:"functions"`
+	Include   json.RawMessage            `json:"$include"`
 }
 
-func (params parameters) parse() tmplParams {
+func (params parameters) parse(rnd *rand.Rand) tmplParams {
 	var functions map[string]interface{}
 	if params.Functions != nil {
-		functions = params.Functions.parse()
+		functions = params.Functions.parseRand(rnd)
 	}
 	var variables = make(map[string]string)
 	for k, v := range params.Variables {
@@ -1,9 +1,17 @@
 package main
 
 import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestServingJSON(t *testing.T) {
@@ -56,8 +64,8 @@ func TestErrorGetDBData(t *testing.T) {
 		{DB: "testdata/db_template_broken.json"},
 	}
 
-	for i, handler := range handlers {
-		err := handler.getDBData()
+	for i := range handlers {
+		err := handlers[i].getDBData()
 		if err == nil {
 			t.Fatalf("Test %d: Expected error, got none", i)
 		}
@@ -119,7 +127,7 @@ func TestHeaders(t *testing.T) {
 		t.Fatalf("Expected allow origin header: %s, got %s", originHeader, CORSHeader)
 	}
 	expectedAcceptedHeaders := "Content-Type, X-Requested-With"
-	respAcceptedHeaders := rec.Header().Get("Access-Control-Allow-Header")
+	respAcceptedHeaders := rec.Header().Get("Access-Control-Allow-Headers")
 	if expectedAcceptedHeaders != respAcceptedHeaders {
 		t.Fatalf("Expected allowed header: %s, got: %s", expectedAcceptedHeaders, respAcceptedHeaders)
 	}
@@ -149,7 +157,7 @@ func TestOPTIONSCall(t *testing.T) {
 		t.Fatalf("Expected allow origin header: %s, got %s", originHeader, CORSHeader)
 	}
 	expectedAcceptedHeaders := "Content-Type, X-Requested-With"
-	respAcceptedHeaders := rec.Header().Get("Access-Control-Allow-Header")
+	respAcceptedHeaders := rec.Header().Get("Access-Control-Allow-Headers")
 	if expectedAcceptedHeaders != respAcceptedHeaders {
 		t.Fatalf("Expected allowed header: %s, got: %s", expectedAcceptedHeaders, respAcceptedHeaders)
 	}
@@ -187,3 +195,576 @@ func TestRandom(t *testing.T) {
 		t.Fatalf("Expected status: %d, got %d", http.StatusOK, rec.Code)
 	}
 }
+
+func TestMethods(t *testing.T) {
+	handler := JSONHandler{DB: "testdata/db_methods.json"}
+	tests := []struct {
+		method          string
+		requestPath     string
+		expectedStatus  int
+		expectedContent string
+		expectedAllow   string
+	}{
+		{
+			method:          "GET",
+			requestPath:     "/users",
+			expectedStatus:  http.StatusOK,
+			expectedContent: `[{"id": 1}]`,
+		},
+		{
+			method:          "POST",
+			requestPath:     "/users",
+			expectedStatus:  http.StatusOK,
+			expectedContent: `{"id": 2}`,
+		},
+		{
+			method:          "PUT",
+			requestPath:     "/users",
+			expectedStatus:  http.StatusMethodNotAllowed,
+			expectedContent: "",
+			expectedAllow:   "GET, HEAD, POST",
+		},
+		{
+			// the server leaves out the body of the answer to HEAD
+			method:          "HEAD",
+			requestPath:     "/users",
+			expectedStatus:  http.StatusOK,
+			expectedContent: `[{"id": 1}]`,
+		},
+		{
+			method:          "PUT",
+			requestPath:     "/mixed",
+			expectedStatus:  http.StatusOK,
+			expectedContent: `{"method": "any"}`,
+		},
+		{
+			method:          "DELETE",
+			requestPath:     "/mixed",
+			expectedStatus:  http.StatusOK,
+			expectedContent: `{"method": "delete"}`,
+		},
+		{
+			method:          "DELETE",
+			requestPath:     "/flat",
+			expectedStatus:  http.StatusOK,
+			expectedContent: `{"field": "value"}`,
+		},
+	}
+	for i, test := range tests {
+		req, err := http.NewRequest(test.method, test.requestPath, nil)
+		if err != nil {
+			t.Fatalf("An error occured when creating the request: %v for test %d", err, i)
+		}
+		rec := httptest.NewRecorder()
+
+		handler.ServeHTTP(rec, req)
+		if rec.Code != test.expectedStatus {
+			t.Fatalf("Test %d, expected status: %d, got %d", i, test.expectedStatus, rec.Code)
+		}
+		respBody := rec.Body.String()
+		if respBody != test.expectedContent {
+			t.Fatalf("Test %d, expected body %s, got %s", i, test.expectedContent, respBody)
+		}
+		allow := rec.Header().Get("Allow")
+		if allow != test.expectedAllow {
+			t.Fatalf("Test %d, expected Allow header %s, got %s", i, test.expectedAllow, allow)
+		}
+	}
+}
+
+func TestStatusAndHeaders(t *testing.T) {
+	handler := JSONHandler{DB: "testdata/db_status.json"}
+	tests := []struct {
+		method          string
+		requestPath     string
+		expectedStatus  int
+		expectedHeaders map[string]string
+	}{
+		{
+			method:         "POST",
+			requestPath:    "/users",
+			expectedStatus: http.StatusCreated,
+			expectedHeaders: map[string]string{
+				"Location":     "/users/2",
+				"Content-Type": "application/json; charset=utf-8",
+			},
+		},
+		{
+			method:         "GET",
+			requestPath:    "/private",
+			expectedStatus: http.StatusUnauthorized,
+			expectedHeaders: map[string]string{
+				"WWW-Authenticate": `Bearer realm="api"`,
+				"Content-Type":     "application/problem+json",
+			},
+		},
+		{
+			method:         "GET",
+			requestPath:    "/default",
+			expectedStatus: http.StatusOK,
+		},
+	}
+	for i, test := range tests {
+		req, err := http.NewRequest(test.method, test.requestPath, nil)
+		if err != nil {
+			t.Fatalf("An error occured when creating the request: %v for test %d", err, i)
+		}
+		rec := httptest.NewRecorder()
+
+		handler.ServeHTTP(rec, req)
+		if rec.Code != test.expectedStatus {
+			t.Fatalf("Test %d, expected status: %d, got %d", i, test.expectedStatus, rec.Code)
+		}
+		for name, expected := range test.expectedHeaders {
+			if value := rec.Header().Get(name); value != expected {
+				t.Fatalf("Test %d, expected header %s: %s, got %s", i, name, expected, value)
+			}
+		}
+	}
+}
+
+func TestPathParameters(t *testing.T) {
+	handler := JSONHandler{DB: "testdata/db_params.json"}
+	tests := []struct {
+		requestPath     string
+		expectedStatus  int
+		expectedContent string
+	}{
+		{
+			requestPath:     "/users/12",
+			expectedStatus:  http.StatusOK,
+			expectedContent: `{"id": "12", "name": "John"}`,
+		},
+		{
+			requestPath:     "/users/a%22b%5Cc",
+			expectedStatus:  http.StatusOK,
+			expectedContent: `{"id": "a\"b\\c", "name": "John"}`,
+		},
+		{
+			requestPath:     "/users/me",
+			expectedStatus:  http.StatusOK,
+			expectedContent: `{"id": "me"}`,
+		},
+		{
+			requestPath:     "/files/docs/readme.md",
+			expectedStatus:  http.StatusOK,
+			expectedContent: `{"path": "docs/readme.md"}`,
+		},
+		{
+			requestPath:     "/users/12/posts",
+			expectedStatus:  http.StatusNotFound,
+			expectedContent: "",
+		},
+	}
+	for i, test := range tests {
+		req, err := http.NewRequest("GET", test.requestPath, nil)
+		if err != nil {
+			t.Fatalf("An error occured when creating the request: %v for test %d", err, i)
+		}
+		rec := httptest.NewRecorder()
+
+		handler.ServeHTTP(rec, req)
+		if rec.Code != test.expectedStatus {
+			t.Fatalf("Test %d, expected status: %d, got %d", i, test.expectedStatus, rec.Code)
+		}
+		respBody := rec.Body.String()
+		if respBody != test.expectedContent {
+			t.Fatalf("Test %d, expected body %s, got %s", i, test.expectedContent, respBody)
+		}
+	}
+}
+
+func TestRequestData(t *testing.T) {
+	handler := JSONHandler{DB: "testdata/db_request.json", PerRequest: true}
+	tests := []struct {
+		method          string
+		requestPath     string
+		headers         map[string]string
+		body            string
+		expectedStatus  int
+		expectedContent string
+	}{
+		{
+			method:          "POST",
+			requestPath:     "/echo?q=search",
+			headers:         map[string]string{"Authorization": "token", "Cookie": "session=abc"},
+			body:            `{"name": "John"}`,
+			expectedStatus:  http.StatusOK,
+			expectedContent: `{"body":{"name":"John"},"method":"POST","query":"search","session":"abc","token":"token"}`,
+		},
+		{
+			method:          "POST",
+			requestPath:     "/echo?q=a%22b%5Cc",
+			headers:         map[string]string{"Authorization": `say "hi"`, "Cookie": "session=abc"},
+			body:            `{"name": "J\"ohn"}`,
+			expectedStatus:  http.StatusOK,
+			expectedContent: `{"body":{"name":"J\"ohn"},"method":"POST","query":"a\"b\\c","session":"abc","token":"say \"hi\""}`,
+		},
+		{
+			method:          "GET",
+			requestPath:     "/echo",
+			expectedStatus:  http.StatusOK,
+			expectedContent: `{"body":{},"method":"GET","query":"","session":"","token":""}`,
+		},
+		{
+			method:          "GET",
+			requestPath:     "/login",
+			headers:         map[string]string{"Authorization": "secret"},
+			expectedStatus:  http.StatusOK,
+			expectedContent: `{"logged":true}`,
+		},
+		{
+			method:          "GET",
+			requestPath:     "/login",
+			expectedStatus:  http.StatusUnauthorized,
+			expectedContent: `{"logged":false}`,
+		},
+	}
+	for i, test := range tests {
+		req, err := http.NewRequest(test.method, test.requestPath, strings.NewReader(test.body))
+		if err != nil {
+			t.Fatalf("An error occured when creating the request: %v for test %d", err, i)
+		}
+		for name, value := range test.headers {
+			req.Header.Set(name, value)
+		}
+		rec := httptest.NewRecorder()
+
+		handler.ServeHTTP(rec, req)
+		if rec.Code != test.expectedStatus {
+			t.Fatalf("Test %d, expected status: %d, got %d", i, test.expectedStatus, rec.Code)
+		}
+		var decoded map[string]interface{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &decoded); err != nil {
+			t.Fatalf("Test %d, the body %s isn't valid JSON: %v", i, rec.Body.String(), err)
+		}
+		respBody, _ := json.Marshal(decoded)
+		if string(respBody) != test.expectedContent {
+			t.Fatalf("Test %d, expected body %s, got %s", i, test.expectedContent, respBody)
+		}
+	}
+}
+
+func TestWatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "iseva")
+	if err != nil {
+		t.Fatalf("An error occured when creating the temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	db := filepath.Join(dir, "db.json")
+	if err := ioutil.WriteFile(db, []byte(`{"urls": {"/test": {"json": 1}}}`), 0644); err != nil {
+		t.Fatalf("An error occured when writing the file: %v", err)
+	}
+	handler := &JSONHandler{DB: db, Watch: true}
+	if err := handler.getDBData(); err != nil {
+		t.Fatalf("An error occured when loading the file: %v", err)
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go handler.watch(5*time.Millisecond, done)
+
+	get := func() string {
+		req, err := http.NewRequest("GET", "/test", nil)
+		if err != nil {
+			t.Fatalf("An error occured when creating the request: %v", err)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Body.String()
+	}
+	waitFor := func(expected string) {
+		for i := 0; i < 200; i++ {
+			if get() == expected {
+				return
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+		t.Fatalf("Expected body %s, got %s", expected, get())
+	}
+
+	waitFor("1")
+	if err := ioutil.WriteFile(db, []byte(`{"urls": {"/test": {"json": 22}}}`), 0644); err != nil {
+		t.Fatalf("An error occured when writing the file: %v", err)
+	}
+	waitFor("22")
+	if err := ioutil.WriteFile(db, []byte(`{"urls": {"/test": {"json": 333}`), 0644); err != nil {
+		t.Fatalf("An error occured when writing the file: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	waitFor("22")
+}
+
+// TestConcurrentRequests is meant to be run with -race: the requests load
+// the file, render it and modify the resources at the same time.
+func TestConcurrentRequests(t *testing.T) {
+	handlers := []*JSONHandler{
+		{DB: "testdata/db_concurrent.json"},
+		{DB: "testdata/db_concurrent.json", Watch: true},
+	}
+	for i, handler := range handlers {
+		var wg sync.WaitGroup
+		for g := 0; g < 8; g++ {
+			wg.Add(1)
+			go func(g int) {
+				defer wg.Done()
+				for n := 0; n < 20; n++ {
+					if handler.Watch {
+						if err := handler.getDBData(); err != nil {
+							t.Errorf("Test %d: an error occured when loading the file: %v", i, err)
+							return
+						}
+					}
+					requests := []struct {
+						method string
+						path   string
+						body   string
+						status int
+					}{
+						{method: "GET", path: "/test/random", status: http.StatusOK},
+						{method: "GET", path: fmt.Sprintf("/users/%d", g), status: http.StatusOK},
+						{method: "POST", path: "/posts", body: `{"title": "post"}`, status: http.StatusCreated},
+						{method: "GET", path: "/posts", status: http.StatusOK},
+					}
+					for _, request := range requests {
+						req, err := http.NewRequest(request.method, request.path, strings.NewReader(request.body))
+						if err != nil {
+							t.Errorf("Test %d: an error occured when creating the request: %v", i, err)
+							return
+						}
+						rec := httptest.NewRecorder()
+						handler.ServeHTTP(rec, req)
+						if rec.Code != request.status {
+							t.Errorf("Test %d: %s %s, expected status: %d, got %d", i, request.method, request.path, request.status, rec.Code)
+						}
+					}
+				}
+			}(g)
+		}
+		wg.Wait()
+	}
+}
+
+func TestSeparator(t *testing.T) {
+	handler := JSONHandler{DB: "testdata/db_dashes.json"}
+	req, err := http.NewRequest("GET", "/test/dashes", nil)
+	if err != nil {
+		t.Fatalf("An error occured when creating the request: %v", err)
+	}
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status: %d, got %d", http.StatusOK, rec.Code)
+	}
+	expectedContent := `{"rule": "---", "dates": "2016-01-01 --- 2016-12-31", "name": "a---b"}`
+	if rec.Body.String() != expectedContent {
+		t.Fatalf("Expected body %s, got %s", expectedContent, rec.Body.String())
+	}
+}
+
+func TestSeed(t *testing.T) {
+	get := func(handler *JSONHandler, seed string) string {
+		req, err := http.NewRequest("GET", "/test/random", nil)
+		if err != nil {
+			t.Fatalf("An error occured when creating the request: %v", err)
+		}
+		if seed != "" {
+			req.Header.Set("X-Iseva-Seed", seed)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status: %d, got %d", http.StatusOK, rec.Code)
+		}
+		return rec.Body.String()
+	}
+	first := get(&JSONHandler{DB: "testdata/db_seed.json"}, "")
+	if second := get(&JSONHandler{DB: "testdata/db_seed.json"}, ""); first != second {
+		t.Fatalf("Expected the same body with the seed of the file, got %s and %s", first, second)
+	}
+	var flagSeed int64 = 7
+	flagged := get(&JSONHandler{DB: "testdata/db_seed.json", Seed: &flagSeed}, "")
+	if flagged == first {
+		t.Fatalf("Expected the seed of the handler to override the one of the file, got %s", flagged)
+	}
+	handler := &JSONHandler{DB: "testdata/db_seed.json", IsStatic: true}
+	if err := handler.getDBData(); err != nil {
+		t.Fatalf("An error occured when loading the file: %v", err)
+	}
+	seeded := get(handler, "1")
+	if again := get(handler, "1"); again != seeded {
+		t.Fatalf("Expected the same body with the same seed header, got %s and %s", seeded, again)
+	}
+	if other := get(handler, "2"); other == seeded {
+		t.Fatalf("Expected a different body with another seed header, got %s", other)
+	}
+
+	req, err := http.NewRequest("GET", "/test/random", nil)
+	if err != nil {
+		t.Fatalf("An error occured when creating the request: %v", err)
+	}
+	req.Header.Set("X-Iseva-Seed", "seed")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status: %d, got %d", http.StatusBadRequest, rec.Code)
+	}
+}
+
+func TestFormats(t *testing.T) {
+	tests := []struct {
+		db             string
+		expectedFormat string
+	}{
+		{db: "testdata/db_yaml.yaml", expectedFormat: "yaml"},
+		{db: "testdata/db_toml.toml", expectedFormat: "toml"},
+		{db: "testdata/db_json5.json5", expectedFormat: "json5"},
+		{db: "testdata/db_sniffed", expectedFormat: "sniffed"},
+	}
+	for i, test := range tests {
+		handler := &JSONHandler{DB: test.db}
+		req, err := http.NewRequest("GET", "/users/12", nil)
+		if err != nil {
+			t.Fatalf("Test %d: an error occured when creating the request: %v", i, err)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Test %d: expected status: %d, got %d with %s", i, http.StatusOK, rec.Code, rec.Body.String())
+		}
+		if format := rec.Header().Get("X-Format"); format != test.expectedFormat {
+			t.Fatalf("Test %d: expected the header of the file, got %s", i, format)
+		}
+		var user struct {
+			ID    int      `json:"id"`
+			Name  string   `json:"name"`
+			Score int      `json:"score"`
+			Tags  []string `json:"tags"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &user); err != nil {
+			t.Fatalf("Test %d: error while unmarshalling the body %s: %v", i, rec.Body.String(), err)
+		}
+		if user.ID != 12 || user.Name != "John" || user.Score < 1 || user.Score > 10 || len(user.Tags) != 2 {
+			t.Fatalf("Test %d: unexpected body %s", i, rec.Body.String())
+		}
+	}
+}
+
+func TestSeveralFiles(t *testing.T) {
+	tests := []struct {
+		db          string
+		url         string
+		expectedKey string
+	}{
+		{db: "testdata/multi", url: "/users/12", expectedKey: "id"},
+		{db: "testdata/multi", url: "/orders", expectedKey: "total"},
+		{db: "testdata/multi/*.json", url: "/users/12", expectedKey: "id"},
+	}
+	for i, test := range tests {
+		handler := &JSONHandler{DB: test.db}
+		req, err := http.NewRequest("GET", test.url, nil)
+		if err != nil {
+			t.Fatalf("Test %d: an error occured when creating the request: %v", i, err)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Test %d: expected status: %d, got %d with %s", i, http.StatusOK, rec.Code, rec.Body.String())
+		}
+		var body map[string]interface{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("Test %d: error while unmarshalling the body %s: %v", i, rec.Body.String(), err)
+		}
+		if body["company"] != "Acme" {
+			t.Fatalf("Test %d: expected the variable of another file, got %s", i, rec.Body.String())
+		}
+		if _, ok := body[test.expectedKey]; !ok {
+			t.Fatalf("Test %d: expected the key %s, got %s", i, test.expectedKey, rec.Body.String())
+		}
+	}
+}
+
+func TestBodies(t *testing.T) {
+	tests := []struct {
+		method              string
+		url                 string
+		expectedStatus      int
+		expectedContentType string
+		expectedBody        string
+	}{
+		{method: "GET", url: "/avatar", expectedStatus: http.StatusOK, expectedContentType: "image/png", expectedBody: "\x89PNG"},
+		{method: "GET", url: "/users.csv", expectedStatus: http.StatusOK, expectedContentType: "text/csv", expectedBody: "id,name\n1,John"},
+		{method: "GET", url: "/notes", expectedStatus: http.StatusOK, expectedContentType: "text/plain; charset=utf-8", expectedBody: "no extension"},
+		{method: "GET", url: "/missing", expectedStatus: http.StatusNotFound, expectedContentType: "application/json; charset=utf-8", expectedBody: "missing.png"},
+		{method: "GET", url: "/page", expectedStatus: http.StatusOK, expectedContentType: "text/html; charset=utf-8", expectedBody: "<html><body>Hello"},
+		{method: "GET", url: "/legacy", expectedStatus: http.StatusOK, expectedContentType: "application/xml", expectedBody: "<user><name>John"},
+		{method: "POST", url: "/legacy", expectedStatus: http.StatusOK, expectedContentType: "application/json; charset=utf-8", expectedBody: `{"id": 1}`},
+		{method: "GET", url: "/text", expectedStatus: http.StatusOK, expectedContentType: "text/plain; charset=utf-8", expectedBody: "plain text"},
+	}
+	handler := &JSONHandler{DB: "testdata/db_bodies.json"}
+	for i, test := range tests {
+		req, err := http.NewRequest(test.method, test.url, nil)
+		if err != nil {
+			t.Fatalf("Test %d: an error occured when creating the request: %v", i, err)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != test.expectedStatus {
+			t.Fatalf("Test %d: expected status: %d, got %d with %s", i, test.expectedStatus, rec.Code, rec.Body.String())
+		}
+		if contentType := rec.Header().Get("Content-Type"); contentType != test.expectedContentType {
+			t.Fatalf("Test %d: expected content type %s, got %s", i, test.expectedContentType, contentType)
+		}
+		if !strings.Contains(rec.Body.String(), test.expectedBody) {
+			t.Fatalf("Test %d: expected the body to contain %q, got %q", i, test.expectedBody, rec.Body.String())
+		}
+	}
+	handler = &JSONHandler{DB: "testdata/db_bodies_broken.json"}
+	if err := handler.getDBData(); err == nil || !strings.Contains(err.Error(), "only one of json, body and file") {
+		t.Fatalf("Expected an error for a route with two bodies, got %v", err)
+	}
+}
+
+func TestVariants(t *testing.T) {
+	tests := []struct {
+		method         string
+		url            string
+		headers        map[string]string
+		body           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{method: "GET", url: "/search?q=foo", expectedStatus: http.StatusOK, expectedBody: `["foo"]`},
+		{method: "GET", url: "/search?q=bar", expectedStatus: http.StatusOK, expectedBody: `["bar", "baz"]`},
+		{method: "GET", url: "/search?q=qux", expectedStatus: http.StatusOK, expectedBody: `[]`},
+		{method: "GET", url: "/me", headers: map[string]string{"Authorization": "Bearer token"}, expectedStatus: http.StatusOK, expectedBody: `{"name": "John"}`},
+		{method: "GET", url: "/me", headers: map[string]string{"Cookie": "session=abc"}, expectedStatus: http.StatusOK, expectedBody: `{"name": "Jane"}`},
+		{method: "GET", url: "/me", headers: map[string]string{"Authorization": "Basic xyz"}, expectedStatus: http.StatusUnauthorized, expectedBody: `{"error": "not logged in"}`},
+		{method: "POST", url: "/orders", body: `{"customer": {"vip": true}, "items": [{"sku": "book"}, {"sku": "gift"}]}`, expectedStatus: http.StatusCreated, expectedBody: `{"free": true}`},
+		{method: "POST", url: "/orders", body: `{"customer": {"name": "Jane"}, "items": [{"sku": "gift"}]}`, expectedStatus: http.StatusCreated, expectedBody: `<order/>`},
+		{method: "POST", url: "/orders", body: `{"customer": {"name": "Bob"}}`, expectedStatus: http.StatusUnprocessableEntity, expectedBody: ``},
+	}
+	handler := &JSONHandler{DB: "testdata/db_variants.json"}
+	for i, test := range tests {
+		req, err := http.NewRequest(test.method, test.url, strings.NewReader(test.body))
+		if err != nil {
+			t.Fatalf("Test %d: an error occured when creating the request: %v", i, err)
+		}
+		for name, value := range test.headers {
+			req.Header.Set(name, value)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != test.expectedStatus {
+			t.Fatalf("Test %d: expected status: %d, got %d with %s", i, test.expectedStatus, rec.Code, rec.Body.String())
+		}
+		if rec.Body.String() != test.expectedBody {
+			t.Fatalf("Test %d: expected body %s, got %s", i, test.expectedBody, rec.Body.String())
+		}
+	}
+	handler = &JSONHandler{DB: "testdata/db_variants_broken.json"}
+	err := handler.getDBData()
+	if le, ok := err.(*loadError); !ok || le.Route != "/search" {
+		t.Fatalf("Expected an error on the route with the invalid regex, got %v", err)
+	}
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// json5ToJSON turns a JSON text with the additions of JSON5 listed in the
+// README into strict JSON. The comments and trailing commas are replaced by spaces and the
+// new lines are kept, so that the lines of the JSON are the ones of the
+// text, and so are the offsets as long as the text is strict JSON.
+func json5ToJSON(text string) (string, error) {
+	c := json5Converter{text: text, line: 1}
+	if err := c.convert(); err != nil {
+		return "", err
+	}
+	return c.out.String(), nil
+}
+
+type json5Converter struct {
+	text string
+	pos  int
+	line int
+	out  bytes.Buffer
+}
+
+func (c *json5Converter) errorf(format string, args ...interface{}) error {
+	column := c.pos - strings.LastIndex(c.text[:c.pos], "\n")
+	return &formatError{Line: c.line, Column: column, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (c *json5Converter) convert() error {
+	for c.pos < len(c.text) {
+		ch := c.text[c.pos]
+		switch {
+		case ch == '/' && strings.HasPrefix(c.text[c.pos:], "//"):
+			end := strings.IndexByte(c.text[c.pos:], '\n')
+			if end < 0 {
+				end = len(c.text) - c.pos
+			}
+			c.blank(end)
+		case ch == '/' && strings.HasPrefix(c.text[c.pos:], "/*"):
+			end := strings.Index(c.text[c.pos+2:], "*/")
+			if end < 0 {
+				return c.errorf("comment not closed")
+			}
+			c.blank(end + 4)
+		case ch == ',':
+			if next := c.significant(c.pos + 1); next < len(c.text) && (c.text[next] == '}' || c.text[next] == ']') {
+				c.out.WriteByte(' ')
+			} else {
+				c.out.WriteByte(',')
+			}
+			c.pos++
+		case ch == '"' || ch == '\'':
+			if err := c.str(ch); err != nil {
+				return err
+			}
+		case ch == '-' || ch == '+' || ch == '.' || (ch >= '0' && ch <= '9'):
+			if err := c.number(); err != nil {
+				return err
+			}
+		case ch == '_' || ch == '$' || ch >= utf8.RuneSelf || unicode.IsLetter(rune(ch)):
+			if err := c.identifier(); err != nil {
+				return err
+			}
+		default:
+			if ch == '\n' {
+				c.line++
+			}
+			c.out.WriteByte(ch)
+			c.pos++
+		}
+	}
+	return nil
+}
+
+// blank replaces the next n bytes by spaces, keeping the new lines.
+func (c *json5Converter) blank(n int) {
+	for _, ch := range []byte(c.text[c.pos : c.pos+n]) {
+		if ch == '\n' {
+			c.line++
+			c.out.WriteByte('\n')
+		} else {
+			c.out.WriteByte(' ')
+		}
+	}
+	c.pos += n
+}
+
+// significant returns the offset of the first byte from pos that isn't a
+// space or in a comment.
+func (c *json5Converter) significant(pos int) int {
+	for pos < len(c.text) {
+		switch {
+		case strings.HasPrefix(c.text[pos:], "//"):
+			end := strings.IndexByte(c.text[pos:], '\n')
+			if end < 0 {
+				return len(c.text)
+			}
+			pos += end
+		case strings.HasPrefix(c.text[pos:], "/*"):
+			end := strings.Index(c.text[pos+2:], "*/")
+			if end < 0 {
+				return len(c.text)
+			}
+			pos += end + 4
+		case strings.IndexByte(" \t\r\n", c.text[pos]) >= 0:
+			pos++
+		default:
+			return pos
+		}
+	}
+	return pos
+}
+
+// str writes a string quoted with quote as a JSON string. The new lines
+// escaped to continue the string on the next line are written after it.
+func (c *json5Converter) str(quote byte) error {
+	c.out.WriteByte('"')
+	c.pos++
+	newLines := 0
+	for {
+		if c.pos >= len(c.text) || c.text[c.pos] == '\n' {
+			return c.errorf("string not closed")
+		}
+		ch := c.text[c.pos]
+		switch {
+		case ch == quote:
+			c.out.WriteByte('"')
+			c.pos++
+			c.out.WriteString(strings.Repeat("\n", newLines))
+			return nil
+		case ch == '"':
+			c.out.WriteString(`\"`)
+			c.pos++
+		case ch == '\\':
+			if c.pos+1 >= len(c.text) {
+				return c.errorf("string not closed")
+			}
+			escaped := c.text[c.pos+1]
+			c.pos += 2
+			switch escaped {
+			case '"', '\\', '/', 'b', 'f', 'n', 'r', 't', 'u':
+				c.out.WriteByte('\\')
+				c.out.WriteByte(escaped)
+			case '\'':
+				c.out.WriteByte('\'')
+			case '\n':
+				newLines++
+				c.line++
+			case '\r':
+				if c.pos < len(c.text) && c.text[c.pos] == '\n' {
+					c.pos++
+				}
+				newLines++
+				c.line++
+			case '0':
+				c.out.WriteString(`\u0000`)
+			case 'v':
+				c.out.WriteString(`\u000b`)
+			case 'x':
+				if c.pos+2 > len(c.text) {
+					return c.errorf("invalid escape \\x")
+				}
+				code, err := strconv.ParseUint(c.text[c.pos:c.pos+2], 16, 8)
+				if err != nil {
+					return c.errorf("invalid escape \\x%s", c.text[c.pos:c.pos+2])
+				}
+				fmt.Fprintf(&c.out, `\u%04x`, code)
+				c.pos += 2
+			default:
+				c.out.WriteByte(escaped)
+			}
+		default:
+			c.out.WriteByte(ch)
+			c.pos++
+		}
+	}
+}
+
+// number writes a JSON5 number as a JSON one: without the plus sign, with
+// the digits around the dot and in decimal.
+func (c *json5Converter) number() error {
+	start := c.pos
+	end := c.pos + 1
+	for end < len(c.text) && (isWordByte(c.text[end]) || c.text[end] == '.' ||
+		((c.text[end] == '+' || c.text[end] == '-') && (c.text[end-1] == 'e' || c.text[end-1] == 'E'))) {
+		end++
+	}
+	literal := c.text[start:end]
+	c.pos = end
+	sign := ""
+	if literal[0] == '-' || literal[0] == '+' {
+		if literal[0] == '-' {
+			sign = "-"
+		}
+		literal = literal[1:]
+	}
+	switch {
+	case literal == "Infinity" || literal == "NaN":
+		return c.errorf("%s can't be written in JSON", c.text[start:end])
+	case strings.HasPrefix(literal, "0x") || strings.HasPrefix(literal, "0X"):
+		value, err := strconv.ParseUint(literal[2:], 16, 64)
+		if err != nil {
+			return c.errorf("invalid number %s", c.text[start:end])
+		}
+		c.out.WriteString(sign + strconv.FormatUint(value, 10))
+		return nil
+	}
+	if strings.HasPrefix(literal, ".") {
+		literal = "0" + literal
+	}
+	if dot := strings.IndexByte(literal, '.'); dot >= 0 && (dot == len(literal)-1 || !isDigit(literal[dot+1])) {
+		literal = literal[:dot+1] + "0" + literal[dot+1:]
+	}
+	if sign == "" && c.text[start] == '+' {
+		// keep the offsets of strict JSON
+		c.out.WriteByte(' ')
+	}
+	c.out.WriteString(sign + literal)
+	return nil
+}
+
+// identifier writes a key without quotes as a JSON string, and checks the
+// other words.
+func (c *json5Converter) identifier() error {
+	end := c.pos
+	for end < len(c.text) {
+		r, size := utf8.DecodeRuneInString(c.text[end:])
+		if r != '_' && r != '$' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			break
+		}
+		end += size
+	}
+	word := c.text[c.pos:end]
+	if next := c.significant(end); next < len(c.text) && c.text[next] == ':' {
+		c.out.WriteString(`"` + word + `"`)
+		c.pos = end
+		return nil
+	}
+	switch word {
+	case "true", "false", "null":
+		c.out.WriteString(word)
+		c.pos = end
+		return nil
+	case "Infinity", "NaN":
+		return c.errorf("%s can't be written in JSON", word)
+	}
+	if word == "" {
+		_, size := utf8.DecodeRuneInString(c.text[c.pos:])
+		word = c.text[c.pos : c.pos+size]
+	}
+	return c.errorf("unexpected %s", word)
+}
+
+func isDigit(ch byte) bool {
+	return ch >= '0' && ch <= '9'
+}
+
+func isWordByte(ch byte) bool {
+	return ch == '_' || isDigit(ch) || (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z')
+}
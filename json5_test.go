@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestJSON5ToJSON(t *testing.T) {
+	tests := []struct {
+		text         string
+		expectedJSON string
+		expectedLine int
+	}{
+		{
+			text:         `{"a": [1, 2.5, "x"], "b": {"c": null}}`,
+			expectedJSON: `{"a": [1, 2.5, "x"], "b": {"c": null}}`,
+		},
+		{
+			text:         "{\n  // a comment\n  \"a\": 1, /* another\n one */\n  \"b\": [1, 2,],\n}",
+			expectedJSON: `{"a": 1, "b": [1, 2]}`,
+		},
+		{
+			text:         `{unquoted: 'single "quoted"', $key_2: 'it\'s'}`,
+			expectedJSON: `{"unquoted": "single \"quoted\"", "$key_2": "it's"}`,
+		},
+		{
+			text:         `{hex: 0x1F, neg: -0xA, plus: +3, lead: .5, trail: 5., exp: 2.e3}`,
+			expectedJSON: `{"hex": 31, "neg": -10, "plus": 3, "lead": 0.5, "trail": 5.0, "exp": 2.0e3}`,
+		},
+		{
+			text:         "{\"s\": \"line \\\ncontinued\", \"x\": \"\\x41\"}",
+			expectedJSON: `{"s": "line continued", "x": "A"}`,
+		},
+		{
+			text:         "{\n  \"a\": Infinity\n}",
+			expectedLine: 2,
+		},
+		{
+			text:         "{\n\n  \"a\": \"not closed\n}",
+			expectedLine: 3,
+		},
+		{
+			text:         "{\"a\": 1 /* not closed",
+			expectedLine: 1,
+		},
+	}
+	for i, test := range tests {
+		out, err := json5ToJSON(test.text)
+		if test.expectedLine > 0 {
+			fe, ok := err.(*formatError)
+			if !ok || fe.Line != test.expectedLine {
+				t.Fatalf("Test %d: expected an error on line %d, got %v", i, test.expectedLine, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Test %d: unexpected error %v", i, err)
+		}
+		if len(out) != len(test.text) && test.text == test.expectedJSON {
+			t.Fatalf("Test %d: expected strict JSON to be kept, got %s", i, out)
+		}
+		assertSameJSON(t, i, test.expectedJSON, out)
+	}
+}
+
+func TestJSON5KeepsLines(t *testing.T) {
+	text := "{\n  // comment\n  a: 'x', /* multi\nline */\n  b: [1,],\n}"
+	out, err := json5ToJSON(text)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if outLine, _ := position(out, len(out)); outLine != 6 {
+		t.Fatalf("Expected the JSON to keep the 6 lines of the text, got %q", out)
+	}
+}
+
+// assertSameJSON fails if the two JSON texts don't hold the same value.
+func assertSameJSON(t *testing.T, i int, expected, actual string) {
+	var expectedValue, actualValue interface{}
+	if err := json.Unmarshal([]byte(expected), &expectedValue); err != nil {
+		t.Fatalf("Test %d: the expected JSON %s is invalid: %v", i, expected, err)
+	}
+	if err := json.Unmarshal([]byte(actual), &actualValue); err != nil {
+		t.Fatalf("Test %d: the JSON %s is invalid: %v", i, actual, err)
+	}
+	if !reflect.DeepEqual(expectedValue, actualValue) {
+		t.Fatalf("Test %d: expected %s, got %s", i, expected, actual)
+	}
+}
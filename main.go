@@ -5,29 +5,86 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
 const (
-	dbPath = "db.json"
+	dbPath        = "db.json"
+	watchInterval = time.Second
 )
 
 var dbFile string
 var staticGen bool
+var perRequest bool
+var watchDB bool
+var stateFile string
+var separator string
+var flagServer serverConfig
+var seed int64
+var checkDB bool
 
 func init() {
-	flag.StringVar(&dbFile, "db", dbPath, "Specify the path of the file in which the JSON is. The default value is db.json")
+	flag.StringVar(&dbFile, "db", dbPath, "Specify the path of the file in which the JSON is, or of a directory or a pattern like mocks/*.json giving several files. The default value is db.json")
 	flag.BoolVar(&staticGen, "s", false, "Specify if you want the JSON file to be loaded on every request or imported in memory and statically serve. This means the random values will be set for the time the program runs. The default value is false")
+	flag.BoolVar(&watchDB, "w", false, "Specify if you want the JSON file to be loaded in memory and loaded again when it changes. If the new version is broken, the previous one keeps being served. The default value is false")
+	flag.BoolVar(&perRequest, "r", false, "Specify if you want the templates to be rendered on every request with the data of the request (query, headers, cookies, body). The default value is false")
+	flag.StringVar(&separator, "sep", defaultSeparator, "Specify the line separating the url part of the JSON file from the template part. The default value is ---")
+	flag.StringVar(&flagServer.Address, "address", "", "Specify the address the server listens on. It overrides the ISEVA_ADDRESS environment variable and the server section of the JSON file. By default it listens on every address")
+	flag.IntVar(&flagServer.Port, "port", 0, "Specify the port the server listens on. It overrides the ISEVA_PORT environment variable and the server section of the JSON file. The default value is 3000")
+	flag.StringVar(&flagServer.BasePath, "base", "", "Specify a path like /api under which the urls are served. It overrides the ISEVA_BASE_PATH environment variable and the server section of the JSON file")
+	flag.Int64Var(&seed, "seed", 0, "Specify the seed of the random values, to get the same values every time. It overrides the seed of the template part of the JSON file. By default the values are different on every run")
+	flag.BoolVar(&checkDB, "check", false, "Specify if you want to check that the JSON file is valid and exit, without starting the server. The errors are printed and the program exits with the status 1 if there are some")
+	flag.StringVar(&stateFile, "state", "", "Specify the path of a file in which the changes made to the resources are saved, and loaded from when starting. By default the changes are lost when the program stops")
 }
 
 func main() {
 	flag.Parse()
 
-	handler, err := NewJSONHandler(dbFile, staticGen)
+	handler := &JSONHandler{DB: dbFile, IsStatic: staticGen, PerRequest: perRequest, Watch: watchDB, State: stateFile, Separator: separator}
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "seed" {
+			handler.Seed = &seed
+		}
+	})
+	if checkDB {
+		if _, err := handler.load(); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		fmt.Printf("%s is valid\n", dbFile)
+		return
+	}
+	if err := handler.getDBData(); err != nil {
+		fmt.Printf("Problem when starting the server: %v\n", err)
+		os.Exit(1)
+	}
+	if watchDB {
+		go handler.watch(watchInterval, nil)
+	}
+	envServer, err := envServerConfig()
 	if err != nil {
 		fmt.Printf("Problem when starting the server: %v\n", err)
 		os.Exit(1)
 	}
+	override := envServer.merge(flagServer)
+	handler.BasePath = override.BasePath
+	server := handler.serverConfig().merge(override)
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-stop
+		if err := handler.Flush(); err != nil {
+			fmt.Printf("Problem when saving the state: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}()
 	http.HandleFunc("/", handler.ServeHTTP)
-	fmt.Print("Starting server\n")
-	http.ListenAndServe(":3000", nil)
+	fmt.Printf("Starting server on %s\n", server.listenAddr())
+	if err := http.ListenAndServe(server.listenAddr(), nil); err != nil {
+		fmt.Printf("Problem when starting the server: %v\n", err)
+		os.Exit(1)
+	}
 }
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// variant is an answer given instead of the one of the route to the
+// requests matching its conditions. The variants are tried in order, and
+// the answer of the route is the default one.
+type variant struct {
+	raw
+	Match *matchConfig `json:"match"`
+}
+
+// matchConfig holds the conditions a request must meet, all of them. The
+// body conditions are keyed by a JSON path.
+type matchConfig struct {
+	Query   map[string]condition `json:"query"`
+	Headers map[string]condition `json:"headers"`
+	Cookies map[string]condition `json:"cookies"`
+	Body    bodyConditions       `json:"body"`
+}
+
+// condition is what a value of the request must be: a JSON value it is
+// equal to, or {"regex": "..."} for a regular expression it matches.
+type condition struct {
+	value  interface{}
+	regexp *regexp.Regexp
+}
+
+type bodyCondition struct {
+	path jsonPath
+	condition
+}
+
+type bodyConditions []bodyCondition
+
+// choose returns the first variant matching the request and its index, or
+// the raw itself and -1 when none does.
+func (r raw) choose(req requestData) (raw, int) {
+	for i, v := range r.Variants {
+		if v.Match.matches(req) {
+			return v.raw, i
+		}
+	}
+	return r, -1
+}
+
+// matches tells if the request meets every condition. A variant without
+// conditions matches every request.
+func (m *matchConfig) matches(req requestData) bool {
+	if m == nil {
+		return true
+	}
+	for name, c := range m.Query {
+		if value, ok := req.Query[name]; !ok || !c.matchesString(value) {
+			return false
+		}
+	}
+	for name, c := range m.Headers {
+		if value, ok := req.Headers[http.CanonicalHeaderKey(name)]; !ok || !c.matchesString(value) {
+			return false
+		}
+	}
+	for name, c := range m.Cookies {
+		if value, ok := req.Cookies[name]; !ok || !c.matchesString(value) {
+			return false
+		}
+	}
+	for _, c := range m.Body {
+		matched := false
+		for _, value := range c.path.values(req.Body) {
+			if c.matchesValue(value) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *condition) UnmarshalJSON(data []byte) error {
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(data, &fields) == nil && len(fields) == 1 && fields["regex"] != nil {
+		var expr string
+		if err := json.Unmarshal(fields["regex"], &expr); err != nil {
+			return fmt.Errorf("regex must be a string")
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return err
+		}
+		c.regexp = re
+		return nil
+	}
+	return json.Unmarshal(data, &c.value)
+}
+
+// matchesString matches a value of the query, the headers or the cookies.
+// A condition that isn't a string is compared to the value as written in
+// JSON, so that 2 matches page=2.
+func (c condition) matchesString(value string) bool {
+	if c.regexp != nil {
+		return c.regexp.MatchString(value)
+	}
+	if s, ok := c.value.(string); ok {
+		return value == s
+	}
+	written, _ := json.Marshal(c.value)
+	return value == string(written)
+}
+
+// matchesValue matches a value of the body. A regular expression matches
+// the values that aren't strings as written in JSON.
+func (c condition) matchesValue(value interface{}) bool {
+	if c.regexp == nil {
+		return reflect.DeepEqual(value, c.value)
+	}
+	s, ok := value.(string)
+	if !ok {
+		written, _ := json.Marshal(value)
+		s = string(written)
+	}
+	return c.regexp.MatchString(s)
+}
+
+func (bc *bodyConditions) UnmarshalJSON(data []byte) error {
+	var conditions map[string]condition
+	if err := json.Unmarshal(data, &conditions); err != nil {
+		return err
+	}
+	for _, key := range sortedKeys(conditions) {
+		path, err := parseJSONPath(key)
+		if err != nil {
+			return fmt.Errorf("body path %q: %v", key, err)
+		}
+		*bc = append(*bc, bodyCondition{path: path, condition: conditions[key]})
+	}
+	return nil
+}
+
+// jsonPath selects values in the body of a request. It supports the keys,
+// the quoted keys, the indexes and the wildcards, like $.user.name,
+// $['first name'], $.items[0].id or $.items[*].id. A path not starting
+// with $ is read from the root of the body, like user.name.
+type jsonPath []pathStep
+
+type pathStep struct {
+	key      string
+	index    int
+	isIndex  bool
+	wildcard bool
+}
+
+func parseJSONPath(path string) (jsonPath, error) {
+	rest := strings.TrimSpace(path)
+	if strings.HasPrefix(rest, "$") {
+		rest = rest[1:]
+	} else {
+		rest = "." + rest
+	}
+	var steps jsonPath
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			if strings.HasPrefix(rest, "*") {
+				steps = append(steps, pathStep{wildcard: true})
+				rest = rest[1:]
+				continue
+			}
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("missing key")
+			}
+			steps = append(steps, pathStep{key: rest[:end]})
+			rest = rest[end:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("missing ]")
+			}
+			inside := strings.TrimSpace(rest[1:end])
+			rest = rest[end+1:]
+			switch {
+			case inside == "*":
+				steps = append(steps, pathStep{wildcard: true})
+			case len(inside) >= 2 && (inside[0] == '\'' || inside[0] == '"') && inside[len(inside)-1] == inside[0]:
+				steps = append(steps, pathStep{key: inside[1 : len(inside)-1]})
+			default:
+				index, err := strconv.Atoi(inside)
+				if err != nil || index < 0 {
+					return nil, fmt.Errorf("invalid index %s", inside)
+				}
+				steps = append(steps, pathStep{index: index, isIndex: true})
+			}
+		default:
+			return nil, fmt.Errorf("unexpected %c", rest[0])
+		}
+	}
+	return steps, nil
+}
+
+// values returns the values selected by the path in value, none if the
+// path doesn't exist.
+func (p jsonPath) values(value interface{}) []interface{} {
+	values := []interface{}{value}
+	for _, step := range p {
+		var next []interface{}
+		for _, v := range values {
+			switch v := v.(type) {
+			case map[string]interface{}:
+				if step.wildcard {
+					for _, key := range sortedKeys(v) {
+						next = append(next, v[key])
+					}
+				} else if child, ok := v[step.key]; ok && !step.isIndex {
+					next = append(next, child)
+				}
+			case []interface{}:
+				if step.wildcard {
+					next = append(next, v...)
+				} else if step.isIndex && step.index < len(v) {
+					next = append(next, v[step.index])
+				}
+			}
+		}
+		values = next
+	}
+	return values
+}
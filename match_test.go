@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestJSONPath(t *testing.T) {
+	var body interface{}
+	json.Unmarshal([]byte(`{"user": {"first name": "John", "tags": ["a", "b"]}, "items": [{"id": 1}, {"id": 2}]}`), &body)
+	tests := []struct {
+		path           string
+		expectedValues string
+		expectedError  bool
+	}{
+		{path: "$.user['first name']", expectedValues: `["John"]`},
+		{path: `user["first name"]`, expectedValues: `["John"]`},
+		{path: "$.user.tags[1]", expectedValues: `["b"]`},
+		{path: "$.items[*].id", expectedValues: `[1,2]`},
+		{path: "$.items.*.id", expectedValues: `[1,2]`},
+		{path: "$.items[5].id", expectedValues: `null`},
+		{path: "$.user.missing", expectedValues: `null`},
+		{path: "$", expectedValues: `[` + `{"items":[{"id":1},{"id":2}],"user":{"first name":"John","tags":["a","b"]}}` + `]`},
+		{path: "$.items[x]", expectedError: true},
+		{path: "$.user[0", expectedError: true},
+		{path: "$..user", expectedError: true},
+	}
+	for i, test := range tests {
+		path, err := parseJSONPath(test.path)
+		if (err != nil) != test.expectedError {
+			t.Fatalf("Test %d: expected error %t, got %v", i, test.expectedError, err)
+		}
+		if err != nil {
+			continue
+		}
+		values, _ := json.Marshal(path.values(body))
+		if string(values) != test.expectedValues {
+			t.Fatalf("Test %d: expected values %s, got %s", i, test.expectedValues, values)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	req := emptyRequestData()
+	req.Query["page"] = "2"
+	req.Headers["X-Api-Key"] = "secret"
+	json.Unmarshal([]byte(`{"amount": 12.5, "user": {"name": "John"}}`), &req.Body)
+	tests := []struct {
+		match         string
+		expectedMatch bool
+	}{
+		{match: `{}`, expectedMatch: true},
+		{match: `{"query": {"page": 2}}`, expectedMatch: true},
+		{match: `{"query": {"page": "3"}}`, expectedMatch: false},
+		{match: `{"query": {"missing": {"regex": ".*"}}}`, expectedMatch: false},
+		{match: `{"headers": {"x-api-key": "secret"}}`, expectedMatch: true},
+		{match: `{"headers": {"x-api-key": "secret"}, "query": {"page": "1"}}`, expectedMatch: false},
+		{match: `{"body": {"amount": 12.5}}`, expectedMatch: true},
+		{match: `{"body": {"amount": "12.5"}}`, expectedMatch: false},
+		{match: `{"body": {"amount": {"regex": "^12\\."}}}`, expectedMatch: true},
+		{match: `{"body": {"$.user": {"name": "John"}}}`, expectedMatch: true},
+	}
+	for i, test := range tests {
+		var m matchConfig
+		if err := json.Unmarshal([]byte(test.match), &m); err != nil {
+			t.Fatalf("Test %d: unexpected error: %v", i, err)
+		}
+		if matched := m.matches(req); matched != test.expectedMatch {
+			t.Fatalf("Test %d: expected match %t, got %t", i, test.expectedMatch, matched)
+		}
+	}
+}
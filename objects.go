@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// object generates a JSON object following its schema, or an array of
+// ArraySize such objects. In the schema, a string "$name" is replaced by
+// the value of the function name, which can be another object, and "$$"
+// at the start of a string stands for a single "$".
+type object struct {
+	Schema    json.RawMessage `json:"schema"`
+	ArraySize int             `json:"arraysize"`
+	Stable    bool            `json:"stable"`
+}
+
+// decodeSchema reads the schema keeping the numbers as they are written.
+func (obj object) decodeSchema() (interface{}, error) {
+	var schema interface{}
+	dec := json.NewDecoder(bytes.NewReader(obj.Schema))
+	dec.UseNumber()
+	if err := dec.Decode(&schema); err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+// references returns the names of the functions used in a schema.
+func references(schema interface{}) []string {
+	var names []string
+	switch v := schema.(type) {
+	case map[string]interface{}:
+		for _, key := range sortedKeys(v) {
+			names = append(names, references(v[key])...)
+		}
+	case []interface{}:
+		for _, elt := range v {
+			names = append(names, references(elt)...)
+		}
+	case string:
+		if name, ok := reference(v); ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func reference(s string) (string, bool) {
+	if strings.HasPrefix(s, "$") && !strings.HasPrefix(s, "$$") {
+		return s[1:], true
+	}
+	return "", false
+}
+
+// objectCycle returns the path of objects referencing themselves starting
+// from name, nil if there is none.
+func (fp funcParams) objectCycle(name string, path []string) []string {
+	for _, seen := range path {
+		if seen == name {
+			return append(path, name)
+		}
+	}
+	obj, ok := fp.Objects[name]
+	if !ok {
+		return nil
+	}
+	schema, err := obj.decodeSchema()
+	if err != nil {
+		return nil
+	}
+	for _, ref := range references(schema) {
+		if cycle := fp.objectCycle(ref, append(path, name)); cycle != nil {
+			return cycle
+		}
+	}
+	return nil
+}
+
+// jsonValued tells if the function name writes JSON, rather than a string
+// written without quotes.
+func (fp funcParams) jsonValued(name string) bool {
+	if _, ok := fp.Arrays[name]; ok {
+		return true
+	}
+	if _, ok := fp.Objects[name]; ok {
+		return true
+	}
+	switch fp.Randoms[name].Type {
+	case "int", "float", "bool":
+		return true
+	}
+	return isChoice(fp.Randoms[name].Type)
+}
+
+// objectFunc returns the function generating the object. The functions it
+// references are looked up in fcts when it is called.
+func (fp funcParams) objectFunc(schema interface{}, arraySize int, fcts map[string]interface{}) func() (string, error) {
+	return func() (string, error) {
+		var value interface{}
+		if arraySize > 0 {
+			values := make([]interface{}, arraySize)
+			for i := range values {
+				v, err := fp.fill(schema, fcts)
+				if err != nil {
+					return "", err
+				}
+				values[i] = v
+			}
+			value = values
+		} else {
+			v, err := fp.fill(schema, fcts)
+			if err != nil {
+				return "", err
+			}
+			value = v
+		}
+		b, err := json.Marshal(value)
+		if err != nil {
+			return "", err
+		}
+		return string(b), nil
+	}
+}
+
+// fill returns a copy of the schema with the references replaced.
+func (fp funcParams) fill(schema interface{}, fcts map[string]interface{}) (interface{}, error) {
+	switch v := schema.(type) {
+	case map[string]interface{}:
+		filled := make(map[string]interface{}, len(v))
+		for _, key := range sortedKeys(v) {
+			value, err := fp.fill(v[key], fcts)
+			if err != nil {
+				return nil, err
+			}
+			filled[key] = value
+		}
+		return filled, nil
+	case []interface{}:
+		filled := make([]interface{}, len(v))
+		for i, elt := range v {
+			value, err := fp.fill(elt, fcts)
+			if err != nil {
+				return nil, err
+			}
+			filled[i] = value
+		}
+		return filled, nil
+	case string:
+		name, ok := reference(v)
+		if !ok {
+			return strings.TrimPrefix(v, "$"), nil
+		}
+		return fp.call(name, fcts)
+	}
+	return schema, nil
+}
+
+// call returns the value of the function name, decoded if it is JSON.
+func (fp funcParams) call(name string, fcts map[string]interface{}) (interface{}, error) {
+	var out string
+	switch f := fcts[name].(type) {
+	case func() string:
+		out = f()
+	case func() (string, error):
+		var err error
+		if out, err = f(); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("function %q not defined", name)
+	}
+	if !fp.jsonValued(name) {
+		return out, nil
+	}
+	return json.RawMessage(out), nil
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const seedHeader = "X-Iseva-Seed"
+
+// requestData is what the template knows about the request being served.
+type requestData struct {
+	Method  string
+	Path    string
+	Params  map[string]string
+	Query   map[string]string
+	Headers map[string]string
+	Cookies map[string]string
+	Body    interface{}
+}
+
+// newRequestData gathers the data of the request for the template. The
+// query and headers only keep their first value. The body is decoded when
+// it is JSON, kept as a string otherwise, and an empty object when there is
+// no body.
+func newRequestData(r *http.Request, params map[string]string) (requestData, error) {
+	data := emptyRequestData()
+	data.Method = r.Method
+	data.Path = r.URL.Path
+	for k, v := range params {
+		data.Params[k] = v
+	}
+	for k, v := range r.URL.Query() {
+		data.Query[k] = v[0]
+	}
+	for k, v := range r.Header {
+		data.Headers[k] = v[0]
+	}
+	for _, cookie := range r.Cookies() {
+		data.Cookies[cookie.Name] = cookie.Value
+	}
+	if r.Body == nil {
+		return data, nil
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return data, err
+	}
+	if len(strings.TrimSpace(string(body))) == 0 {
+		return data, nil
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(body, &decoded); err != nil {
+		data.Body = string(body)
+	} else {
+		data.Body = decoded
+	}
+	return data, nil
+}
+
+func emptyRequestData() requestData {
+	return requestData{
+		Params:  make(map[string]string),
+		Query:   make(map[string]string),
+		Headers: make(map[string]string),
+		Cookies: make(map[string]string),
+		Body:    make(map[string]interface{}),
+	}
+}
+
+// reservedKeys are the keys of the template data holding the request data,
+// which a variable can't use.
+var reservedKeys = []string{"method", "path", "params", "query", "headers", "cookies", "body"}
+
+// checkReserved returns an error naming the first variable using a key
+// reserved for the request data.
+func checkReserved(variables map[string]json.RawMessage) error {
+	for _, key := range reservedKeys {
+		if _, ok := variables[key]; ok {
+			return fmt.Errorf("variable %q uses a key reserved for the request data", key)
+		}
+	}
+	return nil
+}
+
+// fill adds the request data to the template data, under the keys that are
+// reserved for it.
+func (data requestData) fill(tmplData map[string]interface{}) {
+	tmplData["method"] = data.Method
+	tmplData["path"] = data.Path
+	tmplData["params"] = data.Params
+	tmplData["query"] = data.Query
+	tmplData["headers"] = data.Headers
+	tmplData["cookies"] = data.Cookies
+	tmplData["body"] = data.Body
+}
+
+// requestRand returns a source seeded with the X-Iseva-Seed header of the
+// request, nil if there is none.
+func requestRand(r *http.Request) (*rand.Rand, error) {
+	value := r.Header.Get(seedHeader)
+	if value == "" {
+		return nil, nil
+	}
+	seed, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid seed %q, expected an integer", value)
+	}
+	return newRand(seed), nil
+}
+
+// toJSON is available in every template as json, to write back a value
+// coming from the request.
+func toJSON(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
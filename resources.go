@@ -0,0 +1,204 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// resourceStore keeps in memory the collections of the resources section,
+// which are modified by the POST, PUT, PATCH and DELETE requests.
+type resourceStore struct {
+	sync.Mutex
+	collections map[string][]map[string]interface{}
+	statePath   string
+	timer       *time.Timer
+}
+
+func newResourceStore() *resourceStore {
+	return &resourceStore{collections: make(map[string][]map[string]interface{})}
+}
+
+// seed adds the collections of the file that aren't in the store yet, so
+// that reloading the file doesn't lose the changes. The collections that
+// are no longer in the file are removed.
+func (store *resourceStore) seed(resources map[string][]map[string]interface{}) {
+	store.Lock()
+	defer store.Unlock()
+	seeds := make(map[string][]map[string]interface{}, len(resources))
+	for name, items := range resources {
+		seeds[strings.Trim(name, "/")] = items
+	}
+	for name := range store.collections {
+		if _, ok := seeds[name]; !ok {
+			delete(store.collections, name)
+		}
+	}
+	for name, items := range seeds {
+		if _, ok := store.collections[name]; !ok {
+			collection := make([]map[string]interface{}, len(items))
+			copy(collection, items)
+			store.collections[name] = collection
+		}
+	}
+}
+
+// lookup finds the collection and the id, if any, the path refers to.
+func (store *resourceStore) lookup(path string) (string, string, bool) {
+	path = strings.Trim(path, "/")
+	for name := range store.collections {
+		if path == name {
+			return name, "", true
+		}
+		if strings.HasPrefix(path, name+"/") {
+			id := path[len(name)+1:]
+			if id != "" && !strings.Contains(id, "/") {
+				return name, id, true
+			}
+		}
+	}
+	return "", "", false
+}
+
+// serve answers the request if it is for one of the collections, and
+// returns false otherwise. The path of the request is the one under the
+// base path, which is added back to the Location of the items created.
+func (store *resourceStore) serve(w http.ResponseWriter, r *http.Request, basePath string) bool {
+	if store == nil {
+		return false
+	}
+	store.Lock()
+	defer store.Unlock()
+	name, id, ok := store.lookup(r.URL.Path)
+	if !ok {
+		return false
+	}
+	items := store.collections[name]
+	if id == "" {
+		switch r.Method {
+		case "GET":
+			writeResource(w, http.StatusOK, items)
+		case "POST":
+			item, err := decodeItem(r)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, err)
+				return true
+			}
+			if _, ok := item["id"]; !ok {
+				item["id"] = nextID(items)
+			} else if findItem(items, fmt.Sprint(item["id"])) >= 0 {
+				writeError(w, http.StatusConflict, fmt.Errorf("an item with the id %v already exists", item["id"]))
+				return true
+			}
+			store.collections[name] = append(items, item)
+			store.changed()
+			location := fmt.Sprintf("/%s/%v", name, item["id"])
+			if base := strings.Trim(basePath, "/"); base != "" {
+				location = "/" + base + location
+			}
+			w.Header().Set("Location", location)
+			writeResource(w, http.StatusCreated, item)
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+		return true
+	}
+
+	index := findItem(items, id)
+	if index < 0 {
+		w.WriteHeader(http.StatusNotFound)
+		return true
+	}
+	switch r.Method {
+	case "GET":
+		writeResource(w, http.StatusOK, items[index])
+	case "PUT", "PATCH":
+		item, err := decodeItem(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return true
+		}
+		if r.Method == "PATCH" {
+			patched := make(map[string]interface{}, len(items[index]))
+			for k, v := range items[index] {
+				patched[k] = v
+			}
+			for k, v := range item {
+				patched[k] = v
+			}
+			item = patched
+		}
+		item["id"] = items[index]["id"]
+		items[index] = item
+		store.changed()
+		writeResource(w, http.StatusOK, item)
+	case "DELETE":
+		store.collections[name] = append(items[:index:index], items[index+1:]...)
+		store.changed()
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.Header().Set("Allow", "DELETE, GET, PATCH, PUT")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+	return true
+}
+
+func decodeItem(r *http.Request) (map[string]interface{}, error) {
+	if r.Body == nil {
+		return nil, fmt.Errorf("missing body")
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	var item map[string]interface{}
+	if err := json.Unmarshal(body, &item); err != nil {
+		return nil, err
+	}
+	if item == nil {
+		return nil, fmt.Errorf("the body must be a JSON object")
+	}
+	return item, nil
+}
+
+func findItem(items []map[string]interface{}, id string) int {
+	for i, item := range items {
+		if fmt.Sprint(item["id"]) == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// nextID returns the id following the highest numeric id of the items.
+func nextID(items []map[string]interface{}) float64 {
+	var max float64
+	for _, item := range items {
+		if id, err := strconv.ParseFloat(fmt.Sprint(item["id"]), 64); err == nil && id > max {
+			max = id
+		}
+	}
+	return max + 1
+}
+
+func writeResource(w http.ResponseWriter, status int, v interface{}) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	body, _ := json.Marshal(map[string]string{"error": err.Error()})
+	w.WriteHeader(status)
+	w.Write(body)
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestResources(t *testing.T) {
+	handler, err := NewJSONHandler("testdata/db_resources.json", true)
+	if err != nil {
+		t.Fatalf("An error occured when loading the file: %v", err)
+	}
+	tests := []struct {
+		method          string
+		requestPath     string
+		body            string
+		expectedStatus  int
+		expectedContent string
+	}{
+		{
+			method:          "GET",
+			requestPath:     "/users",
+			expectedStatus:  http.StatusOK,
+			expectedContent: `[{"id":1,"name":"John"},{"id":2,"name":"Jane"}]`,
+		},
+		{
+			method:          "GET",
+			requestPath:     "/users/me",
+			expectedStatus:  http.StatusOK,
+			expectedContent: `{"id": "me"}`,
+		},
+		{
+			method:          "POST",
+			requestPath:     "/users",
+			body:            `{"name": "Jack"}`,
+			expectedStatus:  http.StatusCreated,
+			expectedContent: `{"id":3,"name":"Jack"}`,
+		},
+		{
+			method:          "POST",
+			requestPath:     "/users",
+			body:            `{"id": 3, "name": "Joe"}`,
+			expectedStatus:  http.StatusConflict,
+			expectedContent: `{"error":"an item with the id 3 already exists"}`,
+		},
+		{
+			method:          "PATCH",
+			requestPath:     "/users/1",
+			body:            `{"age": 32}`,
+			expectedStatus:  http.StatusOK,
+			expectedContent: `{"age":32,"id":1,"name":"John"}`,
+		},
+		{
+			method:          "PUT",
+			requestPath:     "/users/2",
+			body:            `{"id": 5, "name": "Janet"}`,
+			expectedStatus:  http.StatusOK,
+			expectedContent: `{"id":2,"name":"Janet"}`,
+		},
+		{
+			method:          "DELETE",
+			requestPath:     "/users/1",
+			expectedStatus:  http.StatusNoContent,
+			expectedContent: "",
+		},
+		{
+			method:          "GET",
+			requestPath:     "/users/1",
+			expectedStatus:  http.StatusNotFound,
+			expectedContent: "",
+		},
+		{
+			method:          "GET",
+			requestPath:     "/users",
+			expectedStatus:  http.StatusOK,
+			expectedContent: `[{"id":2,"name":"Janet"},{"id":3,"name":"Jack"}]`,
+		},
+		{
+			method:          "POST",
+			requestPath:     "/users",
+			body:            `[1, 2]`,
+			expectedStatus:  http.StatusBadRequest,
+			expectedContent: `{"error":"json: cannot unmarshal array into Go value of type map[string]interface {}"}`,
+		},
+		{
+			method:          "PUT",
+			requestPath:     "/users",
+			expectedStatus:  http.StatusMethodNotAllowed,
+			expectedContent: "",
+		},
+		{
+			method:          "GET",
+			requestPath:     "/empty",
+			expectedStatus:  http.StatusOK,
+			expectedContent: `[]`,
+		},
+	}
+	for i, test := range tests {
+		req, err := http.NewRequest(test.method, test.requestPath, strings.NewReader(test.body))
+		if err != nil {
+			t.Fatalf("An error occured when creating the request: %v for test %d", err, i)
+		}
+		rec := httptest.NewRecorder()
+
+		handler.ServeHTTP(rec, req)
+		if rec.Code != test.expectedStatus {
+			t.Fatalf("Test %d, expected status: %d, got %d", i, test.expectedStatus, rec.Code)
+		}
+		respBody := rec.Body.String()
+		if respBody != test.expectedContent {
+			t.Fatalf("Test %d, expected body %s, got %s", i, test.expectedContent, respBody)
+		}
+	}
+}
+
+func TestResourcesSeed(t *testing.T) {
+	store := newResourceStore()
+	store.seed(map[string][]map[string]interface{}{
+		"/users": {{"id": 1}},
+		"posts":  {{"id": 1}},
+	})
+	store.collections["users"] = nil
+	store.seed(map[string][]map[string]interface{}{
+		"users": {{"id": 1}, {"id": 2}},
+	})
+	if len(store.collections) != 1 {
+		t.Fatalf("Expected only the users collection to be kept, got %v", store.collections)
+	}
+	if len(store.collections["users"]) != 0 {
+		t.Fatalf("Expected the users collection not to be seeded again, got %v", store.collections["users"])
+	}
+}
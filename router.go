@@ -0,0 +1,110 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// router finds the entry of the urls section answering a request path.
+// Besides the exact paths, an entry can be a pattern where a segment
+// written {name} captures any value and a last segment * captures the rest
+// of the path.
+type router struct {
+	exact    map[string]bool
+	patterns []pattern
+}
+
+type pattern struct {
+	key      string
+	segments []string
+}
+
+const (
+	wildcardSegment = iota
+	paramSegment
+	staticSegment
+)
+
+func newRouter(urls map[string]route) *router {
+	rt := &router{exact: make(map[string]bool)}
+	for key := range urls {
+		if !strings.Contains(key, "{") && !strings.Contains(key, "*") {
+			rt.exact[key] = true
+			continue
+		}
+		rt.patterns = append(rt.patterns, pattern{key: key, segments: strings.Split(key, "/")})
+	}
+	sort.Sort(bySpecificity(rt.patterns))
+	return rt
+}
+
+// match returns the key of the entry for the path and the captured
+// parameters, which are nil when the path is one of the exact ones.
+func (rt *router) match(path string) (string, map[string]string, bool) {
+	if rt == nil {
+		return "", nil, false
+	}
+	if rt.exact[path] {
+		return path, nil, true
+	}
+	segments := strings.Split(path, "/")
+	for _, p := range rt.patterns {
+		if params, ok := p.match(segments); ok {
+			return p.key, params, true
+		}
+	}
+	return "", nil, false
+}
+
+func (p pattern) match(segments []string) (map[string]string, bool) {
+	params := make(map[string]string)
+	for i, segment := range p.segments {
+		switch kind(segment) {
+		case wildcardSegment:
+			if i >= len(segments) {
+				return nil, false
+			}
+			params["*"] = strings.Join(segments[i:], "/")
+			return params, true
+		case paramSegment:
+			if i >= len(segments) || segments[i] == "" {
+				return nil, false
+			}
+			params[segment[1:len(segment)-1]] = segments[i]
+		default:
+			if i >= len(segments) || segments[i] != segment {
+				return nil, false
+			}
+		}
+	}
+	return params, len(segments) == len(p.segments)
+}
+
+func kind(segment string) int {
+	switch {
+	case segment == "*":
+		return wildcardSegment
+	case len(segment) > 2 && segment[0] == '{' && segment[len(segment)-1] == '}':
+		return paramSegment
+	}
+	return staticSegment
+}
+
+// bySpecificity sorts the patterns so that, segment by segment, a static
+// segment comes before a parameter which comes before a wildcard.
+type bySpecificity []pattern
+
+func (s bySpecificity) Len() int      { return len(s) }
+func (s bySpecificity) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s bySpecificity) Less(i, j int) bool {
+	a, b := s[i].segments, s[j].segments
+	for k := 0; k < len(a) && k < len(b); k++ {
+		if ka, kb := kind(a[k]), kind(b[k]); ka != kb {
+			return ka > kb
+		}
+	}
+	if len(a) != len(b) {
+		return len(a) > len(b)
+	}
+	return s[i].key < s[j].key
+}
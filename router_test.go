@@ -0,0 +1,51 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestRouterMatch(t *testing.T) {
+	rt := newRouter(map[string]route{
+		"/users":            {},
+		"/users/me":         {},
+		"/users/{id}":       {},
+		"/users/{id}/posts": {},
+		"/files/*":          {},
+		"/files/{name}":     {},
+		"/files/public/*":   {},
+	})
+	tests := []struct {
+		path           string
+		expectedKey    string
+		expectedParams map[string]string
+		found          bool
+	}{
+		{path: "/users", expectedKey: "/users", found: true},
+		{path: "/users/me", expectedKey: "/users/me", found: true},
+		{path: "/users/12", expectedKey: "/users/{id}", expectedParams: map[string]string{"id": "12"}, found: true},
+		{path: "/users/12/posts", expectedKey: "/users/{id}/posts", expectedParams: map[string]string{"id": "12"}, found: true},
+		{path: "/users/12/comments", found: false},
+		{path: "/users/", found: false},
+		{path: "/files/a.txt", expectedKey: "/files/{name}", expectedParams: map[string]string{"name": "a.txt"}, found: true},
+		{path: "/files/a/b.txt", expectedKey: "/files/*", expectedParams: map[string]string{"*": "a/b.txt"}, found: true},
+		{path: "/files/public/b.txt", expectedKey: "/files/public/*", expectedParams: map[string]string{"*": "b.txt"}, found: true},
+		{path: "/files", found: false},
+	}
+	for i, test := range tests {
+		key, params, ok := rt.match(test.path)
+		if ok != test.found {
+			t.Fatalf("Test %d: when matching %s, expected %t, got %t", i, test.path, test.found, ok)
+		}
+		if key != test.expectedKey {
+			t.Fatalf("Test %d: expected key %s, got %s", i, test.expectedKey, key)
+		}
+		if len(params) != len(test.expectedParams) {
+			t.Fatalf("Test %d: expected params %v, got %v", i, test.expectedParams, params)
+		}
+		for name, value := range test.expectedParams {
+			if params[name] != value {
+				t.Fatalf("Test %d: expected param %s to be %s, got %s", i, name, value, params[name])
+			}
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+const (
+	scenariosPath = "/__iseva/scenarios"
+	startState    = "start"
+)
+
+// scenarioStore keeps the current state of the scenarios, and the number of
+// calls answered by each sequence. They are kept when the files are
+// reloaded.
+type scenarioStore struct {
+	sync.Mutex
+	initial map[string]string
+	states  map[string]string
+	calls   map[string]int
+}
+
+func newScenarioStore() *scenarioStore {
+	return &scenarioStore{
+		initial: make(map[string]string),
+		states:  make(map[string]string),
+		calls:   make(map[string]int),
+	}
+}
+
+// seed sets the initial states given by the scenarios section. A scenario
+// not listed starts in the state start.
+func (store *scenarioStore) seed(initial map[string]string) {
+	store.Lock()
+	defer store.Unlock()
+	store.initial = make(map[string]string, len(initial))
+	for name, state := range initial {
+		store.initial[name] = state
+	}
+}
+
+func (store *scenarioStore) state(name string) string {
+	if state, ok := store.states[name]; ok {
+		return state
+	}
+	if state, ok := store.initial[name]; ok {
+		return state
+	}
+	return startState
+}
+
+// answer returns the answer to serve: the one of the current state of the
+// scenario, then the variant matching the request, then the next step of
+// the sequence. The sequences count their calls for each key, made of the
+// method and the path of the request. The scenario moves to the state
+// given by next in the answer served.
+func (store *scenarioStore) answer(r raw, req requestData, key string) raw {
+	store.Lock()
+	defer store.Unlock()
+	scenario := r.Scenario
+	if scenario != "" {
+		state := store.state(scenario)
+		if stateRaw, ok := r.States[state]; ok {
+			r = stateRaw
+			key += " state " + state
+		}
+	}
+	r, index := r.choose(req)
+	if index >= 0 {
+		key += fmt.Sprintf(" variant %d", index+1)
+	}
+	if len(r.Sequence) > 0 {
+		n := store.calls[key]
+		store.calls[key] = n + 1
+		if n >= len(r.Sequence) {
+			if r.Cycle {
+				n %= len(r.Sequence)
+			} else {
+				n = len(r.Sequence) - 1
+			}
+		}
+		r = r.Sequence[n]
+	}
+	if scenario != "" && r.Next != "" {
+		store.states[scenario] = r.Next
+	}
+	return r
+}
+
+// serve answers the requests of the scenarios endpoint: GET lists the
+// current states, PUT sets the state of a scenario, and DELETE resets a
+// scenario or, without a name, every scenario and sequence.
+func (store *scenarioStore) serve(w http.ResponseWriter, r *http.Request, dbc dbContent) {
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, scenariosPath), "/")
+	store.Lock()
+	defer store.Unlock()
+	names := scenarioNames(dbc)
+	for name := range store.initial {
+		names[name] = true
+	}
+	if name != "" && !names[name] {
+		writeError(w, http.StatusNotFound, fmt.Errorf("unknown scenario %q", name))
+		return
+	}
+	switch {
+	case r.Method == "GET":
+		states := make(map[string]string, len(names))
+		for scenario := range names {
+			states[scenario] = store.state(scenario)
+		}
+		if name != "" {
+			states = map[string]string{name: states[name]}
+		}
+		body, _ := json.Marshal(states)
+		w.WriteHeader(http.StatusOK)
+		w.Write(body)
+	case r.Method == "PUT" && name != "":
+		var body struct {
+			State string `json:"state"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.State == "" {
+			writeError(w, http.StatusBadRequest, fmt.Errorf(`expected a body like {"state": "name"}`))
+			return
+		}
+		store.states[name] = body.State
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == "DELETE" && name != "":
+		delete(store.states, name)
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == "DELETE":
+		store.states = make(map[string]string)
+		store.calls = make(map[string]int)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		if name == "" {
+			w.Header().Set("Allow", "GET, DELETE")
+		} else {
+			w.Header().Set("Allow", "GET, PUT, DELETE")
+		}
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// scenarioNames returns the scenarios used by the routes.
+func scenarioNames(dbc dbContent) map[string]bool {
+	names := make(map[string]bool)
+	for _, rt := range dbc.URLs {
+		if rt.Scenario != "" {
+			names[rt.Scenario] = true
+		}
+		for _, methodRaw := range rt.Methods {
+			if methodRaw.Scenario != "" {
+				names[methodRaw.Scenario] = true
+			}
+		}
+	}
+	return names
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestScenarios(t *testing.T) {
+	tests := []struct {
+		method         string
+		url            string
+		body           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{method: "GET", url: "/jobs/1", expectedStatus: http.StatusOK, expectedBody: `{"status": "pending"}`},
+		{method: "GET", url: "/jobs/1", expectedStatus: http.StatusOK, expectedBody: `{"status": "running"}`},
+		{method: "GET", url: "/jobs/2", expectedStatus: http.StatusOK, expectedBody: `{"status": "pending"}`},
+		{method: "GET", url: "/jobs/1", expectedStatus: http.StatusOK, expectedBody: `{"status": "done"}`},
+		{method: "GET", url: "/jobs/1", expectedStatus: http.StatusOK, expectedBody: `{"status": "done"}`},
+		{method: "GET", url: "/lights", expectedStatus: http.StatusOK, expectedBody: `"on"`},
+		{method: "GET", url: "/lights", expectedStatus: http.StatusOK, expectedBody: `"off"`},
+		{method: "GET", url: "/lights", expectedStatus: http.StatusOK, expectedBody: `"on"`},
+		{method: "GET", url: "/cart", expectedStatus: http.StatusOK, expectedBody: `[]`},
+		{method: "POST", url: "/cart", expectedStatus: http.StatusCreated, expectedBody: `{"id": 1}`},
+		{method: "GET", url: "/cart", expectedStatus: http.StatusOK, expectedBody: `[{"id": 1}]`},
+		{method: "DELETE", url: "/cart", expectedStatus: http.StatusNoContent, expectedBody: ``},
+		{method: "GET", url: "/cart", expectedStatus: http.StatusOK, expectedBody: `[]`},
+		{method: "GET", url: "/token", expectedStatus: http.StatusOK, expectedBody: `{"valid": true}`},
+		{method: "GET", url: "/token", expectedStatus: http.StatusUnauthorized, expectedBody: `{"valid": false}`},
+		{method: "GET", url: scenariosPath, expectedStatus: http.StatusOK, expectedBody: `{"checkout":"empty","session":"expired"}`},
+		{method: "PUT", url: scenariosPath + "/checkout", body: `{"state": "filled"}`, expectedStatus: http.StatusNoContent, expectedBody: ``},
+		{method: "GET", url: "/cart", expectedStatus: http.StatusOK, expectedBody: `[{"id": 1}]`},
+		{method: "GET", url: scenariosPath + "/checkout", expectedStatus: http.StatusOK, expectedBody: `{"checkout":"filled"}`},
+		{method: "DELETE", url: scenariosPath + "/checkout", expectedStatus: http.StatusNoContent, expectedBody: ``},
+		{method: "GET", url: "/cart", expectedStatus: http.StatusOK, expectedBody: `[]`},
+		{method: "PUT", url: scenariosPath + "/checkout", body: `{}`, expectedStatus: http.StatusBadRequest, expectedBody: `{"error":"expected a body like {\"state\": \"name\"}"}`},
+		{method: "PUT", url: scenariosPath + "/unknown", body: `{"state": "a"}`, expectedStatus: http.StatusNotFound, expectedBody: `{"error":"unknown scenario \"unknown\""}`},
+		{method: "DELETE", url: scenariosPath, expectedStatus: http.StatusNoContent, expectedBody: ``},
+		{method: "GET", url: "/jobs/1", expectedStatus: http.StatusOK, expectedBody: `{"status": "pending"}`},
+		{method: "GET", url: "/token", expectedStatus: http.StatusOK, expectedBody: `{"valid": true}`},
+	}
+	handler, err := NewJSONHandler("testdata/db_scenarios.json", true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for i, test := range tests {
+		req, err := http.NewRequest(test.method, test.url, strings.NewReader(test.body))
+		if err != nil {
+			t.Fatalf("Test %d: an error occured when creating the request: %v", i, err)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != test.expectedStatus {
+			t.Fatalf("Test %d: expected status: %d, got %d with %s", i, test.expectedStatus, rec.Code, rec.Body.String())
+		}
+		if rec.Body.String() != test.expectedBody {
+			t.Fatalf("Test %d: expected body %s, got %s", i, test.expectedBody, rec.Body.String())
+		}
+	}
+}
+
+func TestScenariosReload(t *testing.T) {
+	handler := &JSONHandler{DB: "testdata/db_scenarios.json"}
+	for i, expected := range []string{`"on"`, `"off"`, `"on"`} {
+		req, err := http.NewRequest("GET", "/lights", nil)
+		if err != nil {
+			t.Fatalf("Test %d: an error occured when creating the request: %v", i, err)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Body.String() != expected {
+			t.Fatalf("Test %d: expected the sequence to go on when the file is loaded again, got %s", i, rec.Body.String())
+		}
+	}
+}
+
+func TestScenariosError(t *testing.T) {
+	handler := &JSONHandler{DB: "testdata/db_scenarios_broken.json"}
+	err := handler.getDBData()
+	if le, ok := err.(*loadError); !ok || le.Route != "/cart" || !strings.Contains(err.Error(), "need a scenario") {
+		t.Fatalf("Expected an error on the route with next and no scenario, got %v", err)
+	}
+}
+
+func TestScenariosEndpoint(t *testing.T) {
+	handler := &JSONHandler{DB: "testdata/db_scenarios.json", BasePath: "/api"}
+	tests := []struct {
+		method          string
+		url             string
+		headers         map[string]string
+		expectedStatus  int
+		expectedHeaders map[string]string
+	}{
+		{
+			method:          "OPTIONS",
+			url:             "/api" + scenariosPath + "/checkout",
+			headers:         map[string]string{"Origin": "http://localhost:8080", "Access-Control-Request-Method": "PUT"},
+			expectedStatus:  http.StatusNoContent,
+			expectedHeaders: map[string]string{"Access-Control-Allow-Origin": "http://localhost:8080", "Access-Control-Allow-Methods": "GET, HEAD, POST, PUT, PATCH, DELETE"},
+		},
+		{
+			method:          "DELETE",
+			url:             "/api" + scenariosPath,
+			headers:         map[string]string{"Origin": "http://localhost:8080"},
+			expectedStatus:  http.StatusNoContent,
+			expectedHeaders: map[string]string{"Access-Control-Allow-Origin": "http://localhost:8080"},
+		},
+		{
+			method:         "DELETE",
+			url:            scenariosPath,
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+	for i, test := range tests {
+		req, err := http.NewRequest(test.method, test.url, nil)
+		if err != nil {
+			t.Fatalf("Test %d: an error occured when creating the request: %v", i, err)
+		}
+		for name, value := range test.headers {
+			req.Header.Set(name, value)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != test.expectedStatus {
+			t.Fatalf("Test %d: expected status: %d, got %d with %s", i, test.expectedStatus, rec.Code, rec.Body.String())
+		}
+		for name, expected := range test.expectedHeaders {
+			if value := rec.Header().Get(name); value != expected {
+				t.Fatalf("Test %d: expected header %s: %s, got %s", i, name, expected, value)
+			}
+		}
+	}
+}
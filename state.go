@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// stateDebounce is the time to wait after a change before writing the
+// state file, so that a burst of requests only writes it once.
+const stateDebounce = 500 * time.Millisecond
+
+// persist makes the store write its collections to the state file after
+// every change, and loads the collections already saved in it. They take
+// precedence over the ones of the db file.
+func (store *resourceStore) persist(path string) error {
+	store.Lock()
+	defer store.Unlock()
+	store.statePath = path
+	body, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var collections map[string][]map[string]interface{}
+	if err := json.Unmarshal(body, &collections); err != nil {
+		return fmt.Errorf("state file %s: %v", path, err)
+	}
+	for name, items := range collections {
+		store.collections[name] = items
+	}
+	return nil
+}
+
+// changed schedules the writing of the state file. The lock of the store
+// must be held.
+func (store *resourceStore) changed() {
+	if store.statePath == "" {
+		return
+	}
+	if store.timer != nil {
+		store.timer.Stop()
+	}
+	store.timer = time.AfterFunc(stateDebounce, func() {
+		if err := store.flush(); err != nil {
+			fmt.Println(err)
+		}
+	})
+}
+
+// flush writes the state file now if a change is waiting to be written.
+func (store *resourceStore) flush() error {
+	if store == nil {
+		return nil
+	}
+	store.Lock()
+	defer store.Unlock()
+	if store.timer == nil {
+		return nil
+	}
+	store.timer.Stop()
+	store.timer = nil
+	return writeAtomic(store.statePath, store.collections)
+}
+
+// writeAtomic writes the JSON of v in a temporary file renamed to path, so
+// that the state file is never left half written.
+func writeAtomic(path string, v interface{}) error {
+	body, err := json.MarshalIndent(v, "", "    ")
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(body); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStatePersistence(t *testing.T) {
+	dir, err := ioutil.TempDir("", "iseva")
+	if err != nil {
+		t.Fatalf("An error occured when creating the temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	state := filepath.Join(dir, "state.json")
+
+	handler := &JSONHandler{DB: "testdata/db_resources.json", IsStatic: true, State: state}
+	if err := handler.getDBData(); err != nil {
+		t.Fatalf("An error occured when loading the file: %v", err)
+	}
+	req, err := http.NewRequest("POST", "/users", strings.NewReader(`{"name": "Jack"}`))
+	if err != nil {
+		t.Fatalf("An error occured when creating the request: %v", err)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status: %d, got %d", http.StatusCreated, rec.Code)
+	}
+	if _, err := os.Stat(state); !os.IsNotExist(err) {
+		t.Fatalf("Expected the state file to be written after the debounce, got %v", err)
+	}
+	if err := handler.Flush(); err != nil {
+		t.Fatalf("An error occured when saving the state: %v", err)
+	}
+
+	restarted := &JSONHandler{DB: "testdata/db_resources.json", IsStatic: true, State: state}
+	if err := restarted.getDBData(); err != nil {
+		t.Fatalf("An error occured when loading the file again: %v", err)
+	}
+	req, err = http.NewRequest("GET", "/users/3", nil)
+	if err != nil {
+		t.Fatalf("An error occured when creating the request: %v", err)
+	}
+	rec = httptest.NewRecorder()
+	restarted.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status: %d, got %d", http.StatusOK, rec.Code)
+	}
+	expectedContent := `{"id":3,"name":"Jack"}`
+	if rec.Body.String() != expectedContent {
+		t.Fatalf("Expected body %s, got %s", expectedContent, rec.Body.String())
+	}
+}
+
+func TestStateBroken(t *testing.T) {
+	handler := &JSONHandler{DB: "testdata/db_resources.json", State: "testdata/db_simple_broken.json"}
+	if err := handler.getDBData(); err == nil {
+		t.Fatalf("Expected error, got none")
+	}
+}
@@ -0,0 +1,449 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// tomlToJSON turns a TOML text into JSON. It reads the part of TOML 1.0 a
+// db file needs: the tables, the arrays of tables, the inline tables and
+// arrays, and the strings, numbers, booleans and dates. The dates and times
+// are written as strings, as JSON has no type for them, and inf and nan are
+// errors, as JSON can't hold them.
+func tomlToJSON(text string) (string, error) {
+	root := newOrderedMap()
+	p := &tomlParser{
+		text:     strings.Replace(text, "\r\n", "\n", -1),
+		root:     root,
+		current:  root,
+		explicit: make(map[*orderedMap]bool),
+	}
+	if err := p.parse(); err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	writeJSON(&buf, root)
+	return buf.String(), nil
+}
+
+type tomlParser struct {
+	text    string
+	pos     int
+	root    *orderedMap
+	current *orderedMap
+	// explicit are the tables defined by a header, which can't be defined
+	// a second time.
+	explicit map[*orderedMap]bool
+}
+
+func (p *tomlParser) errorf(format string, args ...interface{}) error {
+	line := strings.Count(p.text[:p.pos], "\n") + 1
+	column := p.pos - strings.LastIndex(p.text[:p.pos], "\n")
+	return &formatError{Line: line, Column: column, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *tomlParser) parse() error {
+	for {
+		p.skipBlank()
+		if p.pos >= len(p.text) {
+			return nil
+		}
+		var err error
+		if p.text[p.pos] == '[' {
+			err = p.table()
+		} else {
+			err = p.keyValue(p.current)
+		}
+		if err != nil {
+			return err
+		}
+		if err := p.endOfLine(); err != nil {
+			return err
+		}
+	}
+}
+
+// skipSpaces skips the spaces and tabs.
+func (p *tomlParser) skipSpaces() {
+	for p.pos < len(p.text) && (p.text[p.pos] == ' ' || p.text[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+// skipBlank skips the spaces, the new lines and the comments.
+func (p *tomlParser) skipBlank() {
+	for p.pos < len(p.text) {
+		switch p.text[p.pos] {
+		case ' ', '\t', '\n', '\r':
+			p.pos++
+		case '#':
+			p.skipComment()
+		default:
+			return
+		}
+	}
+}
+
+func (p *tomlParser) skipComment() {
+	if end := strings.IndexByte(p.text[p.pos:], '\n'); end >= 0 {
+		p.pos += end
+	} else {
+		p.pos = len(p.text)
+	}
+}
+
+func (p *tomlParser) endOfLine() error {
+	p.skipSpaces()
+	if p.pos < len(p.text) && p.text[p.pos] == '#' {
+		p.skipComment()
+	}
+	if p.pos < len(p.text) && p.text[p.pos] != '\n' && p.text[p.pos] != '\r' {
+		return p.errorf("expected the end of the line, got %q", p.rest())
+	}
+	return nil
+}
+
+// rest returns the rest of the current line, for the errors.
+func (p *tomlParser) rest() string {
+	rest := p.text[p.pos:]
+	if end := strings.IndexByte(rest, '\n'); end >= 0 {
+		rest = rest[:end]
+	}
+	return rest
+}
+
+// table reads a [table] or [[array of tables]] header, and makes it the
+// table the next keys are added to.
+func (p *tomlParser) table() error {
+	array := strings.HasPrefix(p.text[p.pos:], "[[")
+	if array {
+		p.pos += 2
+	} else {
+		p.pos++
+	}
+	p.skipSpaces()
+	keys, err := p.key()
+	if err != nil {
+		return err
+	}
+	p.skipSpaces()
+	closing := "]"
+	if array {
+		closing = "]]"
+	}
+	if !strings.HasPrefix(p.text[p.pos:], closing) {
+		return p.errorf("expected %s, got %q", closing, p.rest())
+	}
+	p.pos += len(closing)
+	t := p.root
+	for _, key := range keys[:len(keys)-1] {
+		if t, err = p.subTable(t, key); err != nil {
+			return err
+		}
+	}
+	last := keys[len(keys)-1]
+	value, exists := t.get(last)
+	if array {
+		tables, ok := value.([]interface{})
+		if exists && !ok {
+			return p.errorf("key %q already defined", strings.Join(keys, "."))
+		}
+		table := newOrderedMap()
+		t.set(last, append(tables, table))
+		p.current = table
+		return nil
+	}
+	if !exists {
+		table := newOrderedMap()
+		t.set(last, table)
+		value = table
+	}
+	table, ok := value.(*orderedMap)
+	if !ok || p.explicit[table] {
+		return p.errorf("table %q defined twice", strings.Join(keys, "."))
+	}
+	p.explicit[table] = true
+	p.current = table
+	return nil
+}
+
+// subTable returns the table under key in t, created if needed. For an
+// array of tables, it is the last one.
+func (p *tomlParser) subTable(t *orderedMap, key string) (*orderedMap, error) {
+	value, ok := t.get(key)
+	if !ok {
+		table := newOrderedMap()
+		t.set(key, table)
+		return table, nil
+	}
+	switch v := value.(type) {
+	case *orderedMap:
+		return v, nil
+	case []interface{}:
+		if len(v) > 0 {
+			if table, ok := v[len(v)-1].(*orderedMap); ok {
+				return table, nil
+			}
+		}
+	}
+	return nil, p.errorf("key %q isn't a table", key)
+}
+
+// keyValue reads a key = value line, and adds it to t.
+func (p *tomlParser) keyValue(t *orderedMap) error {
+	keys, err := p.key()
+	if err != nil {
+		return err
+	}
+	p.skipSpaces()
+	if p.pos >= len(p.text) || p.text[p.pos] != '=' {
+		return p.errorf("expected = after the key, got %q", p.rest())
+	}
+	p.pos++
+	p.skipSpaces()
+	value, err := p.value()
+	if err != nil {
+		return err
+	}
+	for _, key := range keys[:len(keys)-1] {
+		if t, err = p.subTable(t, key); err != nil {
+			return err
+		}
+	}
+	last := keys[len(keys)-1]
+	if _, exists := t.get(last); exists {
+		return p.errorf("key %q defined twice", strings.Join(keys, "."))
+	}
+	t.set(last, value)
+	return nil
+}
+
+var tomlBareKeyRegexp = regexp.MustCompile(`^[A-Za-z0-9_-]+`)
+
+// key reads a key, made of the parts separated by dots.
+func (p *tomlParser) key() ([]string, error) {
+	var keys []string
+	for {
+		p.skipSpaces()
+		if p.pos >= len(p.text) {
+			return nil, p.errorf("expected a key")
+		}
+		switch p.text[p.pos] {
+		case '"', '\'':
+			s, err := p.str()
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, s)
+		default:
+			bare := tomlBareKeyRegexp.FindString(p.text[p.pos:])
+			if bare == "" {
+				return nil, p.errorf("expected a key, got %q", p.rest())
+			}
+			keys = append(keys, bare)
+			p.pos += len(bare)
+		}
+		p.skipSpaces()
+		if p.pos >= len(p.text) || p.text[p.pos] != '.' {
+			return keys, nil
+		}
+		p.pos++
+	}
+}
+
+func (p *tomlParser) value() (interface{}, error) {
+	if p.pos >= len(p.text) {
+		return nil, p.errorf("expected a value")
+	}
+	switch p.text[p.pos] {
+	case '"', '\'':
+		return p.str()
+	case '[':
+		return p.array()
+	case '{':
+		return p.inlineTable()
+	}
+	for _, word := range []string{"true", "false"} {
+		if strings.HasPrefix(p.text[p.pos:], word) && (len(p.text) == p.pos+len(word) || !isWordByte(p.text[p.pos+len(word)])) {
+			p.pos += len(word)
+			return json.RawMessage(word), nil
+		}
+	}
+	return p.scalar()
+}
+
+func (p *tomlParser) array() (interface{}, error) {
+	values := []interface{}{}
+	p.pos++
+	for {
+		p.skipBlank()
+		if p.pos < len(p.text) && p.text[p.pos] == ']' {
+			p.pos++
+			return values, nil
+		}
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+		p.skipBlank()
+		if p.pos < len(p.text) && p.text[p.pos] == ',' {
+			p.pos++
+		} else if p.pos >= len(p.text) || p.text[p.pos] != ']' {
+			return nil, p.errorf("expected a comma or ] in the array, got %q", p.rest())
+		}
+	}
+}
+
+func (p *tomlParser) inlineTable() (interface{}, error) {
+	table := newOrderedMap()
+	p.pos++
+	for {
+		p.skipBlank()
+		if p.pos < len(p.text) && p.text[p.pos] == '}' {
+			p.pos++
+			return table, nil
+		}
+		if err := p.keyValue(table); err != nil {
+			return nil, err
+		}
+		p.skipBlank()
+		if p.pos < len(p.text) && p.text[p.pos] == ',' {
+			p.pos++
+		} else if p.pos >= len(p.text) || p.text[p.pos] != '}' {
+			return nil, p.errorf("expected a comma or } in the inline table, got %q", p.rest())
+		}
+	}
+}
+
+// str reads a basic or literal string, on one line or several.
+func (p *tomlParser) str() (string, error) {
+	quote := p.text[p.pos]
+	delimiter := string(quote)
+	multiline := strings.HasPrefix(p.text[p.pos:], strings.Repeat(delimiter, 3))
+	if multiline {
+		delimiter = strings.Repeat(delimiter, 3)
+		p.pos += 3
+		// a new line right after the opening quotes isn't part of the string
+		if strings.HasPrefix(p.text[p.pos:], "\n") {
+			p.pos++
+		}
+	} else {
+		p.pos++
+	}
+	var buf bytes.Buffer
+	for {
+		if p.pos >= len(p.text) || (!multiline && p.text[p.pos] == '\n') {
+			return "", p.errorf("string not closed")
+		}
+		if strings.HasPrefix(p.text[p.pos:], delimiter) {
+			p.pos += len(delimiter)
+			// up to two quotes can end the string before the closing ones
+			for i := 0; multiline && i < 2 && p.pos < len(p.text) && p.text[p.pos] == quote; i++ {
+				buf.WriteByte(quote)
+				p.pos++
+			}
+			return buf.String(), nil
+		}
+		ch := p.text[p.pos]
+		if ch != '\\' || quote == '\'' {
+			buf.WriteByte(ch)
+			p.pos++
+			continue
+		}
+		if err := p.escape(&buf, multiline); err != nil {
+			return "", err
+		}
+	}
+}
+
+var tomlEscapes = map[byte]string{
+	'b': "\b", 't': "\t", 'n': "\n", 'f': "\f", 'r': "\r", 'e': "\x1b", '"': "\"", '\\': "\\",
+}
+
+// escape writes the character escaped at the current position.
+func (p *tomlParser) escape(buf *bytes.Buffer, multiline bool) error {
+	if p.pos+1 >= len(p.text) {
+		return p.errorf("string not closed")
+	}
+	escaped := p.text[p.pos+1]
+	if unescaped, ok := tomlEscapes[escaped]; ok {
+		buf.WriteString(unescaped)
+		p.pos += 2
+		return nil
+	}
+	if multiline && strings.TrimLeft(p.rest()[1:], " \t\r") == "" {
+		// a backslash ending a line removes the spaces until the next text
+		p.pos++
+		for p.pos < len(p.text) && strings.IndexByte(" \t\r\n", p.text[p.pos]) >= 0 {
+			p.pos++
+		}
+		return nil
+	}
+	size := map[byte]int{'x': 2, 'u': 4, 'U': 8}[escaped]
+	if size == 0 || p.pos+2+size > len(p.text) {
+		return p.errorf("invalid escape \\%c", escaped)
+	}
+	code, err := strconv.ParseUint(p.text[p.pos+2:p.pos+2+size], 16, 32)
+	if err != nil {
+		return p.errorf("invalid escape %s", p.text[p.pos:p.pos+2+size])
+	}
+	buf.WriteRune(rune(code))
+	p.pos += 2 + size
+	return nil
+}
+
+var (
+	tomlDateRegexp  = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+	tomlTimeRegexp  = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2}|\d{2}:\d{2})`)
+	tomlIntRegexp   = regexp.MustCompile(`^[-+]?(0|[1-9][0-9]*)$`)
+	tomlFloatRegexp = regexp.MustCompile(`^[-+]?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][-+]?[0-9]+)?$`)
+)
+
+// scalar reads a number, a date or a time.
+func (p *tomlParser) scalar() (interface{}, error) {
+	start := p.pos
+	p.word()
+	// a date and a time can be separated by a space
+	if tomlDateRegexp.MatchString(p.text[start:p.pos]) && p.pos+1 < len(p.text) && p.text[p.pos] == ' ' && isDigit(p.text[p.pos+1]) {
+		p.pos++
+		p.word()
+	}
+	literal := p.text[start:p.pos]
+	if literal == "" {
+		return nil, p.errorf("expected a value, got %q", p.rest())
+	}
+	if tomlTimeRegexp.MatchString(literal) {
+		return literal, nil
+	}
+	digits := strings.Replace(literal, "_", "", -1)
+	switch {
+	case strings.HasPrefix(digits, "0x") || strings.HasPrefix(digits, "0o") || strings.HasPrefix(digits, "0b"):
+		base := map[byte]int{'x': 16, 'o': 8, 'b': 2}[digits[1]]
+		if v, err := strconv.ParseUint(digits[2:], base, 64); err == nil {
+			return json.RawMessage(strconv.FormatUint(v, 10)), nil
+		}
+	case tomlIntRegexp.MatchString(digits):
+		return json.RawMessage(strings.TrimPrefix(digits, "+")), nil
+	case tomlFloatRegexp.MatchString(digits):
+		if v, err := strconv.ParseFloat(digits, 64); err == nil {
+			return json.RawMessage(strconv.FormatFloat(v, 'g', -1, 64)), nil
+		}
+	case strings.HasSuffix(digits, "inf") || strings.HasSuffix(digits, "nan"):
+		p.pos = start
+		return nil, p.errorf("%s can't be written in JSON", literal)
+	}
+	p.pos = start
+	return nil, p.errorf("invalid value %q", literal)
+}
+
+// word moves after the characters of a number, a date or a time.
+func (p *tomlParser) word() {
+	for p.pos < len(p.text) && (isWordByte(p.text[p.pos]) || strings.IndexByte(":.+-", p.text[p.pos]) >= 0) {
+		p.pos++
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestTOMLToJSON(t *testing.T) {
+	tests := []struct {
+		text         string
+		expectedJSON string
+		expectedLine int
+	}{
+		{
+			text: `
+# a comment
+title = "mock" # another one
+
+[urls."/test".json]
+field = "value"
+count = 1_000
+ratio = 1.5
+ok = true
+hex = 0xff
+date = 2020-01-02
+datetime = 1979-05-27 07:32:00Z
+literal = 'C:\path'
+escaped = "a\tb\u00e9"
+
+[urls."/test".headers]
+Location = "/test/1"
+`,
+			expectedJSON: `{"title": "mock", "urls": {"/test": {"json": {"field": "value", "count": 1000, "ratio": 1.5, "ok": true, "hex": 255, "date": "2020-01-02", "datetime": "1979-05-27 07:32:00Z", "literal": "C:\\path", "escaped": "a\tbé"}, "headers": {"Location": "/test/1"}}}}`,
+		},
+		{
+			text: `
+a.b.c = 1
+inline = { x = 1, y = [1, 2,], z = { w = "v" } }
+array = [
+  "one", # first
+  "two",
+]
+multi = """
+first
+second \
+   joined"""
+raw = '''
+no \escape'''
+
+[[users]]
+name = "John"
+
+[[users]]
+name = "Jane"
+`,
+			expectedJSON: `{"a": {"b": {"c": 1}}, "inline": {"x": 1, "y": [1, 2], "z": {"w": "v"}}, "array": ["one", "two"], "multi": "first\nsecond joined", "raw": "no \\escape", "users": [{"name": "John"}, {"name": "Jane"}]}`,
+		},
+		{
+			text:         "a = 1\na = 2",
+			expectedLine: 2,
+		},
+		{
+			text:         "[t]\na = 1\n\n[t]\nb = 2",
+			expectedLine: 4,
+		},
+		{
+			text:         "a = 1\nb = \"not closed\n",
+			expectedLine: 2,
+		},
+		{
+			text:         "a = 1 b = 2",
+			expectedLine: 1,
+		},
+		{
+			text:         "\na = nan",
+			expectedLine: 2,
+		},
+	}
+	for i, test := range tests {
+		out, err := tomlToJSON(test.text)
+		if test.expectedLine > 0 {
+			fe, ok := err.(*formatError)
+			if !ok || fe.Line != test.expectedLine {
+				t.Fatalf("Test %d: expected an error on line %d, got %v", i, test.expectedLine, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Test %d: unexpected error %v", i, err)
+		}
+		assertSameJSON(t, i, test.expectedJSON, out)
+	}
+}
@@ -2,7 +2,7 @@ package util
 
 import (
 	"math/rand"
-	"time"
+	"sync"
 )
 
 const letterBytes = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
@@ -12,9 +12,31 @@ const (
 	letterIdxMax  = 63 / letterIdxBits   // # of letter indices fitting in 63 bits
 )
 
-var src = rand.NewSource(time.Now().UnixNano())
+// LockedSource is a rand.Source safe for concurrent use.
+type LockedSource struct {
+	mu  sync.Mutex
+	src rand.Source
+}
+
+func NewSource(seed int64) *LockedSource {
+	return &LockedSource{src: rand.NewSource(seed)}
+}
+
+func (s *LockedSource) Int63() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.src.Int63()
+}
+
+func (s *LockedSource) Seed(seed int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.src.Seed(seed)
+}
 
-func RandString(n int) string {
+// RandStringFrom returns a random string of n letters taken from the given
+// source.
+func RandStringFrom(src rand.Source, n int) string {
 	b := make([]byte, n)
 	for i, cache, remain := n-1, src.Int63(), letterIdxMax; i >= 0; {
 		if remain == 0 {
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// functionError is a function of the template part that can't be built.
+type functionError struct {
+	Kind   string
+	Name   string
+	Reason string
+}
+
+func (e functionError) Error() string {
+	return fmt.Sprintf("%s function %q: %s", e.Kind, e.Name, e.Reason)
+}
+
+// functionErrors are all the invalid functions of a file.
+type functionErrors []functionError
+
+func (errs functionErrors) Error() string {
+	lines := make([]string, len(errs))
+	for i, err := range errs {
+		lines[i] = "    " + err.Error()
+	}
+	return fmt.Sprintf("%d invalid functions:\n%s", len(errs), strings.Join(lines, "\n"))
+}
+
+// validate returns the reasons why the functions can't be built, nil if
+// they all can.
+func (fp funcParams) validate() error {
+	var errs functionErrors
+	for _, name := range sortedKeys(fp.Randoms) {
+		if reason := fp.Randoms[name].check(); reason != "" {
+			errs = append(errs, functionError{"rand", name, reason})
+		}
+	}
+	for _, name := range sortedKeys(fp.Arrays) {
+		if _, ok := fp.Randoms[name]; ok {
+			errs = append(errs, functionError{"array", name, "already defined as a rand function"})
+			continue
+		}
+		arrParam := fp.Arrays[name]
+		if arrParam.ArraySize <= 0 {
+			errs = append(errs, functionError{"array", name, fmt.Sprintf("arraysize %d must be positive", arrParam.ArraySize)})
+			continue
+		}
+		if reason := arrParam.element().check(); reason != "" {
+			errs = append(errs, functionError{"array", name, reason})
+		}
+	}
+	for _, name := range sortedKeys(fp.Objects) {
+		if reason := fp.checkObject(name); reason != "" {
+			errs = append(errs, functionError{"object", name, reason})
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// check returns why the random function can't be built, an empty string
+// if it can.
+func (p random) check() string {
+	switch {
+	case p.Type == "":
+		return "no type"
+	case isChoice(p.Type):
+		if len(p.Values) == 0 {
+			return "no values to pick from"
+		}
+		if p.Type == "weighted" && len(p.Weights) > 0 && len(p.Weights) != len(p.Values) {
+			return fmt.Sprintf("%d weights for %d values", len(p.Weights), len(p.Values))
+		}
+		for _, w := range p.Weights {
+			if w < 0 {
+				return fmt.Sprintf("negative weight %v", w)
+			}
+		}
+		if p.Type == "sample" && p.Min < 0 {
+			return fmt.Sprintf("min %d can't be negative for a sample", p.Min)
+		}
+		if p.Type == "sample" && p.Size < 0 {
+			return fmt.Sprintf("size %d can't be negative for a sample", p.Size)
+		}
+	case p.Type == "date":
+		for _, date := range []string{p.From, p.To} {
+			if _, err := parseDate(date); date != "" && err != nil {
+				return fmt.Sprintf("invalid date %q, expected 2006-01-02 or 2006-01-02T15:04:05Z07:00", date)
+			}
+		}
+	case fakers[p.Type] != nil:
+	case p.Type == "int" || p.Type == "float":
+		if p.Max <= p.Min {
+			return fmt.Sprintf("min %d must be lower than max %d", p.Min, p.Max)
+		}
+	case p.Type == "string":
+		if p.Max > p.Min && p.Min < 0 {
+			return fmt.Sprintf("min %d can't be negative for a string", p.Min)
+		}
+		if p.Max <= p.Min && p.Size <= 0 {
+			return "needs a positive size, or a min lower than max"
+		}
+	default:
+		return fmt.Sprintf("unknown type %q", p.Type)
+	}
+	return ""
+}
+
+// checkObject returns why the object function can't be built, an empty
+// string if it can.
+func (fp funcParams) checkObject(name string) string {
+	if _, ok := fp.Randoms[name]; ok {
+		return "already defined as a rand function"
+	}
+	if _, ok := fp.Arrays[name]; ok {
+		return "already defined as an array function"
+	}
+	obj := fp.Objects[name]
+	if len(obj.Schema) == 0 {
+		return "no schema"
+	}
+	schema, err := obj.decodeSchema()
+	if err != nil {
+		return fmt.Sprintf("invalid schema: %v", err)
+	}
+	if obj.ArraySize < 0 {
+		return fmt.Sprintf("arraysize %d can't be negative", obj.ArraySize)
+	}
+	if cycle := fp.objectCycle(name, nil); cycle != nil {
+		return fmt.Sprintf("references itself through %s", strings.Join(cycle, " -> "))
+	}
+	for _, ref := range references(schema) {
+		if !fp.defined(ref) {
+			return fmt.Sprintf("references the undefined function %q", ref)
+		}
+	}
+	return ""
+}
+
+// defined tells if there is a function called name.
+func (fp funcParams) defined(name string) bool {
+	_, isRandom := fp.Randoms[name]
+	_, isArray := fp.Arrays[name]
+	_, isObject := fp.Objects[name]
+	return isRandom || isArray || isObject
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		params         funcParams
+		expectedReason string
+	}{
+		{
+			params:         funcParams{Randoms: map[string]random{"f": random{Type: "int", Min: 5, Max: 3}}},
+			expectedReason: `rand function "f": min 5 must be lower than max 3`,
+		},
+		{
+			params:         funcParams{Randoms: map[string]random{"f": random{Type: "str", Size: 3}}},
+			expectedReason: `rand function "f": unknown type "str"`,
+		},
+		{
+			params:         funcParams{Randoms: map[string]random{"f": random{Size: 3}}},
+			expectedReason: `rand function "f": no type`,
+		},
+		{
+			params:         funcParams{Randoms: map[string]random{"f": random{Type: "string"}}},
+			expectedReason: `rand function "f": needs a positive size, or a min lower than max`,
+		},
+		{
+			params:         funcParams{Randoms: map[string]random{"f": random{Type: "string", Min: -2, Max: 3}}},
+			expectedReason: `rand function "f": min -2 can't be negative for a string`,
+		},
+		{
+			params:         funcParams{Randoms: map[string]random{"f": random{Type: "date", From: "yesterday"}}},
+			expectedReason: `rand function "f": invalid date "yesterday"`,
+		},
+		{
+			params:         funcParams{Randoms: map[string]random{"f": random{Type: "choice"}}},
+			expectedReason: `rand function "f": no values to pick from`,
+		},
+		{
+			params: funcParams{Randoms: map[string]random{"f": random{
+				Type:    "weighted",
+				Values:  []json.RawMessage{json.RawMessage(`1`), json.RawMessage(`2`)},
+				Weights: []float64{1},
+			}}},
+			expectedReason: `rand function "f": 1 weights for 2 values`,
+		},
+		{
+			params: funcParams{Randoms: map[string]random{"f": random{
+				Type:   "sample",
+				Values: []json.RawMessage{json.RawMessage(`1`), json.RawMessage(`2`), json.RawMessage(`3`)},
+				Min:    -5,
+				Max:    2,
+			}}},
+			expectedReason: `rand function "f": min -5 can't be negative for a sample`,
+		},
+		{
+			params:         funcParams{Arrays: map[string]array{"f": array{Type: "int", Min: 0, Max: 3}}},
+			expectedReason: `array function "f": arraysize 0 must be positive`,
+		},
+		{
+			params:         funcParams{Arrays: map[string]array{"f": array{Type: "float", ArraySize: 2}}},
+			expectedReason: `array function "f": min 0 must be lower than max 0`,
+		},
+		{
+			params: funcParams{
+				Randoms: map[string]random{"f": random{Type: "bool"}},
+				Arrays:  map[string]array{"f": array{Type: "bool", ArraySize: 2}},
+			},
+			expectedReason: `array function "f": already defined as a rand function`,
+		},
+		{
+			params:         funcParams{Objects: map[string]object{"f": object{Schema: json.RawMessage(`{"id": "$id"}`)}}},
+			expectedReason: `object function "f": references the undefined function "id"`,
+		},
+		{
+			params: funcParams{Objects: map[string]object{
+				"f": object{Schema: json.RawMessage(`{"g": "$g"}`)},
+				"g": object{Schema: json.RawMessage(`["$f"]`)},
+			}},
+			expectedReason: `object function "f": references itself through f -> g -> f`,
+		},
+		{
+			params:         funcParams{Objects: map[string]object{"f": object{}}},
+			expectedReason: `object function "f": no schema`,
+		},
+	}
+	for i, test := range tests {
+		err := test.params.validate()
+		if err == nil {
+			t.Fatalf("Test %d: expected an error", i)
+		}
+		if !strings.Contains(err.Error(), test.expectedReason) {
+			t.Fatalf("Test %d: expected the error to contain %s, got %v", i, test.expectedReason, err)
+		}
+	}
+	valid := funcParams{
+		Randoms: map[string]random{
+			"id":    random{Type: "int", Min: 0, Max: 10},
+			"name":  random{Type: "string", Size: 10},
+			"email": random{Type: "email"},
+		},
+		Arrays:  map[string]array{"ids": array{Type: "int", ArraySize: 3, Min: 0, Max: 10}},
+		Objects: map[string]object{"user": object{Schema: json.RawMessage(`{"id": "$id", "name": "$name", "email": "$email", "price": "$$3"}`)}},
+	}
+	if err := valid.validate(); err != nil {
+		t.Fatalf("Expected the functions to be valid, got %v", err)
+	}
+}
+
+func TestValidateFile(t *testing.T) {
+	handler := JSONHandler{DB: "testdata/db_functions_invalid.json"}
+	err := handler.getDBData()
+	le, ok := err.(*loadError)
+	if !ok {
+		t.Fatalf("Expected a load error, got %T: %v", err, err)
+	}
+	if le.Section != templateSection {
+		t.Fatalf("Expected the error to be in the %s, got %s", templateSection, le.Section)
+	}
+	errs, ok := le.Err.(functionErrors)
+	if !ok || len(errs) != 4 {
+		t.Fatalf("Expected the 4 invalid functions to be reported, got %v", le.Err)
+	}
+	for _, name := range []string{`"color"`, `"int"`, `"empty"`, `"user"`} {
+		if !strings.Contains(err.Error(), name) {
+			t.Fatalf("Expected the function %s to be reported, got %v", name, err)
+		}
+	}
+}
+
+func TestValidateDirectory(t *testing.T) {
+	handler := JSONHandler{DB: "testdata/multi_invalid"}
+	err := handler.getDBData()
+	le, ok := err.(*loadError)
+	if !ok {
+		t.Fatalf("Expected a load error, got %T: %v", err, err)
+	}
+	if expected := filepath.Join("testdata", "multi_invalid", "functions.json"); le.File != expected {
+		t.Fatalf("Expected the error in %s, got %s", expected, le.File)
+	}
+}
@@ -0,0 +1,694 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// yamlToJSON turns a YAML text into JSON. It reads a deliberate subset of
+// YAML 1.2, the one a db file needs: the block and flow styles, the quoted
+// and plain scalars and the literal and folded blocks, typed with the core
+// schema. Anchors, aliases, tags, complex keys and several documents are
+// errors, and so are .inf and .nan which JSON can't hold. A merge key << is
+// read as a plain key, and the YAML 1.1 values like yes are strings.
+func yamlToJSON(text string) (string, error) {
+	p := &yamlParser{lines: strings.Split(strings.Replace(text, "\r\n", "\n", -1), "\n")}
+	p.skipDirectives()
+	value, err := p.block(0)
+	if err != nil {
+		return "", err
+	}
+	if p.next() {
+		if content := strings.TrimSpace(p.lines[p.pos]); content != "..." {
+			return "", p.errorf("unexpected %q", content)
+		}
+	}
+	var buf bytes.Buffer
+	writeJSON(&buf, value)
+	return buf.String(), nil
+}
+
+type yamlParser struct {
+	lines []string
+	pos   int
+	// indents replaces the indentation of a line whose beginning was read,
+	// like the dash of a sequence entry holding a mapping.
+	indents map[int]int
+}
+
+func (p *yamlParser) errorf(format string, args ...interface{}) error {
+	return &formatError{Line: p.pos + 1, Msg: fmt.Sprintf(format, args...)}
+}
+
+// skipDirectives skips the directives and the start of the document.
+func (p *yamlParser) skipDirectives() {
+	for p.next() {
+		line := strings.TrimSpace(p.lines[p.pos])
+		if !strings.HasPrefix(line, "%") && line != "---" {
+			return
+		}
+		p.pos++
+	}
+}
+
+// next moves to the next line with content, false if there is none.
+func (p *yamlParser) next() bool {
+	for ; p.pos < len(p.lines); p.pos++ {
+		line := strings.TrimSpace(p.lines[p.pos])
+		if line != "" && !strings.HasPrefix(line, "#") {
+			return true
+		}
+	}
+	return false
+}
+
+// line returns the indentation and the content of the current line.
+func (p *yamlParser) line() (int, string, error) {
+	raw := p.lines[p.pos]
+	content := strings.TrimLeft(raw, " ")
+	indent := len(raw) - len(content)
+	if strings.HasPrefix(content, "\t") {
+		return 0, "", p.errorf("tabs can't be used to indent")
+	}
+	if i, ok := p.indents[p.pos]; ok {
+		indent = i
+	}
+	return indent, strings.TrimRight(content, " \t\r"), nil
+}
+
+// setLine replaces the current line by its content from column on, as if
+// the beginning was spaces.
+func (p *yamlParser) setLine(column int, content string) {
+	if p.indents == nil {
+		p.indents = make(map[int]int)
+	}
+	p.indents[p.pos] = column
+	p.lines[p.pos] = content
+}
+
+// block reads the value starting on the next line, indented at least by
+// minIndent. There is none, null, if the next line is less indented.
+func (p *yamlParser) block(minIndent int) (interface{}, error) {
+	if !p.next() {
+		return nil, nil
+	}
+	indent, content, err := p.line()
+	if err != nil {
+		return nil, err
+	}
+	if indent < minIndent || content == "---" || content == "..." {
+		return nil, nil
+	}
+	if isSequenceEntry(content) {
+		return p.sequence(indent)
+	}
+	if _, _, ok, err := p.splitKey(content); err != nil {
+		return nil, err
+	} else if ok {
+		return p.mapping(indent)
+	}
+	return p.inline(content, minIndent-1)
+}
+
+func isSequenceEntry(content string) bool {
+	return content == "-" || strings.HasPrefix(content, "- ")
+}
+
+func (p *yamlParser) sequence(indent int) (interface{}, error) {
+	values := []interface{}{}
+	for p.next() {
+		lineIndent, content, err := p.line()
+		if err != nil {
+			return nil, err
+		}
+		if lineIndent < indent || !isSequenceEntry(content) {
+			break
+		}
+		if lineIndent > indent {
+			return nil, p.errorf("bad indentation of a sequence entry")
+		}
+		rest := strings.TrimLeft(content[1:], " ")
+		var value interface{}
+		switch {
+		case rest == "" || strings.HasPrefix(rest, "#"):
+			p.pos++
+			value, err = p.block(indent + 1)
+		case p.isBlockStart(rest):
+			// the entry holds a mapping or a sequence starting on this line
+			p.setLine(indent+len(content)-len(rest), rest)
+			value, err = p.block(indent + 1)
+		default:
+			value, err = p.inline(rest, indent)
+		}
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// isBlockStart tells if the content starts a mapping or a sequence.
+func (p *yamlParser) isBlockStart(content string) bool {
+	if isSequenceEntry(content) {
+		return true
+	}
+	_, _, ok, _ := p.splitKey(content)
+	return ok
+}
+
+func (p *yamlParser) mapping(indent int) (interface{}, error) {
+	m := newOrderedMap()
+	for p.next() {
+		lineIndent, content, err := p.line()
+		if err != nil {
+			return nil, err
+		}
+		if lineIndent < indent || content == "---" || content == "..." {
+			break
+		}
+		if lineIndent > indent {
+			return nil, p.errorf("bad indentation of a mapping entry")
+		}
+		if isSequenceEntry(content) {
+			break
+		}
+		key, rest, ok, err := p.splitKey(content)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, p.errorf("expected a key followed by a colon, got %q", content)
+		}
+		if _, dup := m.get(key); dup {
+			return nil, p.errorf("key %q defined twice", key)
+		}
+		var value interface{}
+		if rest == "" || strings.HasPrefix(rest, "#") {
+			p.pos++
+			value, err = p.block(indent + 1)
+			if err == nil && value == nil && p.next() {
+				// a sequence can be written at the indentation of its key
+				if i, c, _ := p.line(); i == indent && isSequenceEntry(c) {
+					value, err = p.sequence(indent)
+				}
+			}
+		} else {
+			value, err = p.inline(rest, indent)
+		}
+		if err != nil {
+			return nil, err
+		}
+		m.set(key, value)
+	}
+	return m, nil
+}
+
+// splitKey splits a line of a mapping in its key and the rest of the line
+// after the colon. ok is false if the line isn't a mapping entry.
+func (p *yamlParser) splitKey(content string) (key, rest string, ok bool, err error) {
+	if content == "" || strings.IndexByte("[{|>#&*!%@`", content[0]) >= 0 || isSequenceEntry(content) {
+		if strings.HasPrefix(content, "&") || strings.HasPrefix(content, "*") {
+			return "", "", false, p.errorf("anchors and aliases aren't supported")
+		}
+		if strings.HasPrefix(content, "!") {
+			return "", "", false, p.errorf("tags aren't supported")
+		}
+		return "", "", false, nil
+	}
+	if strings.HasPrefix(content, "? ") || content == "?" {
+		return "", "", false, p.errorf("complex keys aren't supported")
+	}
+	if content[0] == '"' || content[0] == '\'' {
+		s, end, err := p.quoted(content)
+		if err != nil {
+			// a string going on the next lines isn't a key
+			return "", "", false, nil
+		}
+		after := strings.TrimLeft(content[end:], " ")
+		if !strings.HasPrefix(after, ":") {
+			return "", "", false, nil
+		}
+		return s, strings.TrimSpace(after[1:]), true, nil
+	}
+	for i := 0; i < len(content); i++ {
+		if content[i] == '#' && i > 0 && content[i-1] == ' ' {
+			return "", "", false, nil
+		}
+		if content[i] == ':' && (i == len(content)-1 || content[i+1] == ' ') {
+			return strings.TrimSpace(content[:i]), strings.TrimSpace(content[i+1:]), true, nil
+		}
+	}
+	return "", "", false, nil
+}
+
+// inline reads the value written on the current line after a key or a
+// dash, which can go on the lines indented more than indent.
+func (p *yamlParser) inline(content string, indent int) (interface{}, error) {
+	switch content[0] {
+	case '&', '*':
+		return nil, p.errorf("anchors and aliases aren't supported")
+	case '!':
+		return nil, p.errorf("tags aren't supported")
+	case '|', '>':
+		p.pos++
+		return p.blockScalar(content, indent)
+	case '[', '{':
+		text, err := p.flowText(content)
+		if err != nil {
+			return nil, err
+		}
+		f := yamlFlow{p: p, text: text}
+		value, err := f.value()
+		if err != nil {
+			return nil, err
+		}
+		if f.skipSpaces(); f.pos < len(f.text) && f.text[f.pos] != '#' {
+			return nil, p.errorf("unexpected %q after the flow collection", f.text[f.pos:])
+		}
+		return value, nil
+	case '"', '\'':
+		text := content
+		for !quoteClosed(text) && p.pos+1 < len(p.lines) {
+			p.pos++
+			text += "\n" + strings.TrimSpace(p.lines[p.pos])
+		}
+		s, end, err := p.quoted(text)
+		if err != nil {
+			return nil, err
+		}
+		if rest := strings.TrimSpace(text[end:]); rest != "" && !strings.HasPrefix(rest, "#") {
+			return nil, p.errorf("unexpected %q after the string", rest)
+		}
+		p.pos++
+		return s, nil
+	}
+	value := stripComment(content)
+	line := p.pos + 1
+	p.pos++
+	// a plain scalar goes on while the lines are more indented
+	for p.next() {
+		lineIndent, next, err := p.line()
+		if err != nil {
+			return nil, err
+		}
+		if lineIndent <= indent || strings.HasPrefix(next, "#") {
+			break
+		}
+		if _, _, ok, _ := p.splitKey(next); ok {
+			return nil, p.errorf("bad indentation of a mapping entry")
+		}
+		value += " " + stripComment(next)
+		p.pos++
+	}
+	scalar, err := yamlScalar(value)
+	if err != nil {
+		return nil, &formatError{Line: line, Msg: err.Error()}
+	}
+	return scalar, nil
+}
+
+// stripComment removes the comment ending a plain scalar.
+func stripComment(content string) string {
+	if i := strings.Index(content, " #"); i >= 0 {
+		content = content[:i]
+	}
+	return strings.TrimSpace(content)
+}
+
+// quoteClosed tells if the quoted string starting the text is closed.
+func quoteClosed(text string) bool {
+	quote := text[0]
+	for i := 1; i < len(text); i++ {
+		switch {
+		case quote == '"' && text[i] == '\\':
+			i++
+		case text[i] == quote && quote == '\'' && i+1 < len(text) && text[i+1] == '\'':
+			i++
+		case text[i] == quote:
+			return true
+		}
+	}
+	return false
+}
+
+// quoted reads the quoted string starting the text, and returns where it
+// ends. The new lines inside are folded like in YAML.
+func (p *yamlParser) quoted(text string) (string, int, error) {
+	quote := text[0]
+	var buf bytes.Buffer
+	for i := 1; i < len(text); i++ {
+		ch := text[i]
+		switch {
+		case ch == quote && quote == '\'' && i+1 < len(text) && text[i+1] == '\'':
+			buf.WriteByte('\'')
+			i++
+		case ch == quote:
+			return buf.String(), i + 1, nil
+		case ch == '\n':
+			// a new line is a space, and an empty line a new line
+			trimmed := strings.TrimRight(buf.String(), " ")
+			buf.Reset()
+			buf.WriteString(trimmed)
+			if i+1 < len(text) && text[i+1] == '\n' {
+				for i+1 < len(text) && text[i+1] == '\n' {
+					buf.WriteByte('\n')
+					i++
+				}
+			} else {
+				buf.WriteByte(' ')
+			}
+		case ch == '\\' && quote == '"':
+			if i+1 >= len(text) {
+				return "", 0, p.errorf("string not closed")
+			}
+			i++
+			n, err := yamlEscape(&buf, text[i:])
+			if err != nil {
+				return "", 0, p.errorf("%v", err)
+			}
+			i += n
+		default:
+			buf.WriteByte(ch)
+		}
+	}
+	return "", 0, p.errorf("string not closed")
+}
+
+var yamlEscapes = map[byte]string{
+	'0': "\x00", 'a': "\a", 'b': "\b", 't': "\t", '\t': "\t", 'n': "\n", 'v': "\v", 'f': "\f",
+	'r': "\r", 'e': "\x1b", ' ': " ", '"': "\"", '/': "/", '\\': "\\", 'N': "\u0085",
+	'_': " ", 'L': " ", 'P': " ",
+}
+
+// yamlEscape writes the character escaped at the start of s, and returns
+// the number of bytes read after the first one.
+func yamlEscape(buf *bytes.Buffer, s string) (int, error) {
+	if unescaped, ok := yamlEscapes[s[0]]; ok {
+		buf.WriteString(unescaped)
+		return 0, nil
+	}
+	if s[0] == '\n' {
+		// the line goes on after the new line, without spaces
+		return 0, nil
+	}
+	size := map[byte]int{'x': 2, 'u': 4, 'U': 8}[s[0]]
+	if size == 0 || len(s) <= size {
+		return 0, fmt.Errorf("invalid escape \\%c", s[0])
+	}
+	code, err := strconv.ParseUint(s[1:size+1], 16, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid escape \\%s", s[:size+1])
+	}
+	buf.WriteRune(rune(code))
+	return size, nil
+}
+
+// flowText returns the text of a flow collection, which can go on several
+// lines until its brackets are closed.
+func (p *yamlParser) flowText(content string) (string, error) {
+	text := content
+	start := p.pos
+	for {
+		depth, inQuote := 0, byte(0)
+		for i := 0; i < len(text); i++ {
+			ch := text[i]
+			switch {
+			case inQuote != 0:
+				if ch == '\\' && inQuote == '"' {
+					i++
+				} else if ch == inQuote {
+					inQuote = 0
+				}
+			case ch == '"' || ch == '\'':
+				inQuote = ch
+			case ch == '#' && (i == 0 || text[i-1] == ' ' || text[i-1] == '\n'):
+				// the comment goes until the end of the line
+				end := strings.IndexByte(text[i:], '\n')
+				if end < 0 {
+					end = len(text) - i
+				}
+				text = text[:i] + text[i+end:]
+				i--
+			case ch == '[' || ch == '{':
+				depth++
+			case ch == ']' || ch == '}':
+				depth--
+			}
+		}
+		if depth <= 0 {
+			p.pos++
+			return text, nil
+		}
+		if p.pos+1 >= len(p.lines) {
+			p.pos = start
+			return "", p.errorf("flow collection not closed")
+		}
+		p.pos++
+		text += "\n" + strings.TrimSpace(p.lines[p.pos])
+	}
+}
+
+// blockScalar reads a literal (|) or folded (>) block, made of the lines
+// more indented than indent.
+func (p *yamlParser) blockScalar(header string, indent int) (interface{}, error) {
+	header = stripComment(header)
+	literal := header[0] == '|'
+	chomping, contentIndent := byte(0), 0
+	for _, ch := range []byte(header[1:]) {
+		switch {
+		case ch == '-' || ch == '+':
+			chomping = ch
+		case ch >= '1' && ch <= '9':
+			contentIndent = indent + int(ch-'0')
+			if indent < 0 {
+				contentIndent = int(ch - '0')
+			}
+		default:
+			return nil, p.errorf("invalid block header %q", header)
+		}
+	}
+	var lines []string
+	for ; p.pos < len(p.lines); p.pos++ {
+		raw := strings.TrimRight(p.lines[p.pos], "\r")
+		content := strings.TrimLeft(raw, " ")
+		lineIndent := len(raw) - len(content)
+		if content == "" {
+			lines = append(lines, "")
+			continue
+		}
+		if contentIndent == 0 {
+			if lineIndent <= indent {
+				break
+			}
+			contentIndent = lineIndent
+		}
+		if lineIndent < contentIndent {
+			break
+		}
+		lines = append(lines, raw[contentIndent:])
+	}
+	// the empty lines at the end are only kept with +
+	end := len(lines)
+	for end > 0 && strings.TrimSpace(lines[end-1]) == "" {
+		end--
+	}
+	trailing := lines[end:]
+	lines = lines[:end]
+	var buf bytes.Buffer
+	for i, line := range lines {
+		if i > 0 {
+			previous := lines[i-1]
+			switch {
+			case literal || strings.HasPrefix(line, " ") || strings.HasPrefix(previous, " "):
+				buf.WriteByte('\n')
+			case line == "":
+				// an empty line folds in a new line
+				buf.WriteByte('\n')
+				continue
+			case previous != "":
+				buf.WriteByte(' ')
+			}
+		}
+		buf.WriteString(line)
+	}
+	switch {
+	case chomping == '+':
+		buf.WriteString(strings.Repeat("\n", len(trailing)+1))
+	case chomping != '-' && len(lines) > 0:
+		buf.WriteByte('\n')
+	}
+	return buf.String(), nil
+}
+
+// yamlFlow reads a flow collection: [a, b] or {a: b}.
+type yamlFlow struct {
+	p    *yamlParser
+	text string
+	pos  int
+}
+
+func (f *yamlFlow) skipSpaces() {
+	for f.pos < len(f.text) && strings.IndexByte(" \t\r\n", f.text[f.pos]) >= 0 {
+		f.pos++
+	}
+}
+
+func (f *yamlFlow) value() (interface{}, error) {
+	f.skipSpaces()
+	if f.pos >= len(f.text) {
+		return nil, f.p.errorf("flow collection not closed")
+	}
+	switch f.text[f.pos] {
+	case '[':
+		return f.sequence()
+	case '{':
+		return f.mapping()
+	case '"', '\'':
+		s, end, err := f.p.quoted(f.text[f.pos:])
+		if err != nil {
+			return nil, err
+		}
+		f.pos += end
+		return s, nil
+	case '&', '*':
+		return nil, f.p.errorf("anchors and aliases aren't supported")
+	case '!':
+		return nil, f.p.errorf("tags aren't supported")
+	}
+	start := f.pos
+	for f.pos < len(f.text) && strings.IndexByte(",]}", f.text[f.pos]) < 0 &&
+		!(f.text[f.pos] == ':' && (f.pos+1 == len(f.text) || strings.IndexByte(" ,]}\n", f.text[f.pos+1]) >= 0)) {
+		f.pos++
+	}
+	scalar, err := yamlScalar(strings.Join(strings.Fields(f.text[start:f.pos]), " "))
+	if err != nil {
+		return nil, f.p.errorf("%v", err)
+	}
+	return scalar, nil
+}
+
+func (f *yamlFlow) sequence() (interface{}, error) {
+	values := []interface{}{}
+	f.pos++
+	for {
+		f.skipSpaces()
+		if f.pos < len(f.text) && f.text[f.pos] == ']' {
+			f.pos++
+			return values, nil
+		}
+		value, err := f.value()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+		if err := f.separator(']'); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (f *yamlFlow) mapping() (interface{}, error) {
+	m := newOrderedMap()
+	f.pos++
+	for {
+		f.skipSpaces()
+		if f.pos < len(f.text) && f.text[f.pos] == '}' {
+			f.pos++
+			return m, nil
+		}
+		keyValue, err := f.value()
+		if err != nil {
+			return nil, err
+		}
+		key, ok := keyValue.(string)
+		if !ok {
+			var buf bytes.Buffer
+			writeJSON(&buf, keyValue)
+			key = buf.String()
+		}
+		if _, dup := m.get(key); dup {
+			return nil, f.p.errorf("key %q defined twice", key)
+		}
+		var value interface{}
+		if f.skipSpaces(); f.pos < len(f.text) && f.text[f.pos] == ':' {
+			f.pos++
+			f.skipSpaces()
+			if f.pos < len(f.text) && f.text[f.pos] != ',' && f.text[f.pos] != '}' {
+				if value, err = f.value(); err != nil {
+					return nil, err
+				}
+			}
+		}
+		m.set(key, value)
+		if err := f.separator('}'); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// separator reads the comma between two entries, leaving the closing
+// bracket to be read.
+func (f *yamlFlow) separator(closing byte) error {
+	f.skipSpaces()
+	if f.pos >= len(f.text) {
+		return f.p.errorf("flow collection not closed")
+	}
+	switch f.text[f.pos] {
+	case ',':
+		f.pos++
+		return nil
+	case closing:
+		return nil
+	}
+	return f.p.errorf("expected a comma or %q, got %q", closing, f.text[f.pos:])
+}
+
+var (
+	yamlIntRegexp   = regexp.MustCompile(`^[-+]?[0-9]+$`)
+	yamlFloatRegexp = regexp.MustCompile(`^[-+]?(\.[0-9]+|[0-9]+(\.[0-9]*)?)([eE][-+]?[0-9]+)?$`)
+)
+
+// yamlScalar types a plain scalar as in the core schema of YAML 1.2: null,
+// boolean, integer, float or string.
+func yamlScalar(s string) (interface{}, error) {
+	switch s {
+	case "", "~", "null", "Null", "NULL":
+		return nil, nil
+	case "true", "True", "TRUE":
+		return json.RawMessage("true"), nil
+	case "false", "False", "FALSE":
+		return json.RawMessage("false"), nil
+	case ".inf", ".Inf", ".INF", "+.inf", "-.inf", ".nan", ".NaN", ".NAN":
+		return nil, fmt.Errorf("%s can't be written in JSON", s)
+	}
+	switch {
+	case yamlIntRegexp.MatchString(s):
+		digits := strings.TrimLeft(strings.TrimLeft(s, "+-"), "0")
+		if digits == "" {
+			digits = "0"
+		}
+		if strings.HasPrefix(s, "-") {
+			digits = "-" + digits
+		}
+		return json.RawMessage(digits), nil
+	case strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0o"):
+		base := 16
+		if s[1] == 'o' {
+			base = 8
+		}
+		if v, err := strconv.ParseUint(s[2:], base, 64); err == nil {
+			return json.RawMessage(strconv.FormatUint(v, 10)), nil
+		}
+	case yamlFloatRegexp.MatchString(s):
+		if v, err := strconv.ParseFloat(s, 64); err == nil && !math.IsInf(v, 0) {
+			return json.RawMessage(strconv.FormatFloat(v, 'g', -1, 64)), nil
+		}
+	}
+	return s, nil
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestYAMLToJSON(t *testing.T) {
+	tests := []struct {
+		text         string
+		expectedJSON string
+		expectedLine int
+	}{
+		{
+			text: `
+# a comment
+urls:
+  /test:
+    json:
+      field: value # a comment
+      count: 3
+      ratio: 1.5
+      ok: true
+      none: null
+      empty:
+      quoted: "a \"b\"\tc"
+      single: 'it''s'
+      url: http://example.com/a#b
+`,
+			expectedJSON: `{"urls": {"/test": {"json": {"field": "value", "count": 3, "ratio": 1.5, "ok": true, "none": null, "empty": null, "quoted": "a \"b\"\tc", "single": "it's", "url": "http://example.com/a#b"}}}}`,
+		},
+		{
+			text: `---
+list:
+- a
+- 2
+- - nested
+  - list
+- name: John
+  roles:
+    - admin
+    - user
+-
+  name: Jane
+`,
+			expectedJSON: `{"list": ["a", 2, ["nested", "list"], {"name": "John", "roles": ["admin", "user"]}, {"name": "Jane"}]}`,
+		},
+		{
+			text:         `flow: {a: 1, "b": [x, "y", {c: d}], e: , f: 'g, h'}` + "\nempty: []\njson: {\"id\":1,\"tags\":[\"a\",\"b\"]}",
+			expectedJSON: `{"flow": {"a": 1, "b": ["x", "y", {"c": "d"}], "e": null, "f": "g, h"}, "empty": [], "json": {"id": 1, "tags": ["a", "b"]}}`,
+		},
+		{
+			text: `multi: [
+  1, # one
+  2,
+]
+`,
+			expectedJSON: `{"multi": [1, 2]}`,
+		},
+		{
+			text: `literal: |
+  line 1
+    indented
+  line 3
+folded: >
+  some
+  folded
+
+  text
+strip: |-
+  no new line
+plain: a long
+  plain scalar
+`,
+			expectedJSON: `{"literal": "line 1\n  indented\nline 3\n", "folded": "some folded\ntext\n", "strip": "no new line", "plain": "a long plain scalar"}`,
+		},
+		{
+			text:         "numbers: [007, +12, 0x1F, 0o17, 1e3, -.5, 1.0.0]",
+			expectedJSON: `{"numbers": [7, 12, 31, 15, 1000, -0.5, "1.0.0"]}`,
+		},
+		{
+			text:         "<<: merged\nanswer: yes\nswitch: on",
+			expectedJSON: `{"<<": "merged", "answer": "yes", "switch": "on"}`,
+		},
+		{
+			text:         "a: 1\n  b: 2",
+			expectedLine: 2,
+		},
+		{
+			text:         "a: 1\nb: *anchor",
+			expectedLine: 2,
+		},
+		{
+			text:         "a: 1\nb: !!str 2",
+			expectedLine: 2,
+		},
+		{
+			text:         "a: 1\n? b\n: 2",
+			expectedLine: 2,
+		},
+		{
+			text:         "a: 1\n---\nb: 2",
+			expectedLine: 2,
+		},
+		{
+			text:         "a: 1\na: 2",
+			expectedLine: 2,
+		},
+		{
+			text:         "a:\n  b: &anchor 1",
+			expectedLine: 2,
+		},
+		{
+			text:         "a: [1, 2",
+			expectedLine: 1,
+		},
+		{
+			text:         "a: 1\nb: .inf",
+			expectedLine: 2,
+		},
+	}
+	for i, test := range tests {
+		out, err := yamlToJSON(test.text)
+		if test.expectedLine > 0 {
+			fe, ok := err.(*formatError)
+			if !ok || fe.Line != test.expectedLine {
+				t.Fatalf("Test %d: expected an error on line %d, got %v", i, test.expectedLine, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Test %d: unexpected error %v", i, err)
+		}
+		assertSameJSON(t, i, test.expectedJSON, out)
+	}
+}